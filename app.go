@@ -7,59 +7,24 @@ package app
 import (
 	"context"
 	"fmt"
-	"github.com/fatih/color"
 	"github.com/marmotedu/errors"
 	"github.com/moby/term"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
+	"github.com/yuanbaopig/app/featuregate"
+	"github.com/yuanbaopig/app/flagvalue"
 	"github.com/yuanbaopig/app/fname"
+	"github.com/yuanbaopig/app/logging"
+	"github.com/yuanbaopig/app/validation"
 	"github.com/yuanbaopig/app/version"
 	"github.com/yuanbaopig/app/version/verflag"
 	"io"
 	"os"
 	"strings"
-)
-
-var (
-	progressMessage = color.GreenString("==>")
-
-	usageTemplate = fmt.Sprintf(`%s{{if .Runnable}}
-  %s{{end}}{{if .HasAvailableSubCommands}}
-  %s{{end}}{{if gt (len .Aliases) 0}}
-
-%s
-  {{.NameAndAliases}}{{end}}{{if .HasExample}}
-
-%s
-{{.Example}}{{end}}{{if .HasAvailableSubCommands}}
-
-%s{{range .Commands}}{{if (or .IsAvailableCommand (eq .Name "help"))}}
-  %s {{.Short}}{{end}}{{end}}{{end}}{{if .HasAvailableLocalFlags}}
-
-%s
-{{.LocalFlags.FlagUsages | trimTrailingWhitespaces}}{{end}}{{if .HasAvailableInheritedFlags}}
-
-%s
-{{.InheritedFlags.FlagUsages | trimTrailingWhitespaces}}{{end}}{{if .HasHelpSubCommands}}
-
-%s{{range .Commands}}{{if .IsAdditionalHelpTopicCommand}}
-  {{rpad .CommandPath .CommandPathPadding}} {{.Short}}{{end}}{{end}}{{end}}{{if .HasAvailableSubCommands}}
-
-Use "%s --help" for more information about a command.{{end}}
-`,
-		color.CyanString("Usage:"),
-		color.GreenString("{{.UseLine}}"),
-		color.GreenString("{{.CommandPath}} [command]"),
-		color.CyanString("Aliases:"),
-		color.CyanString("Examples:"),
-		color.CyanString("Available Commands:"),
-		color.GreenString("{{rpad .Name .NamePadding }}"),
-		color.CyanString("Flags:"),
-		color.CyanString("Global Flags:"),
-		color.CyanString("Additional help topics:"),
-		color.GreenString("{{.CommandPath}} [command]"),
-	)
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // App is the main structure of a cli application.
@@ -69,15 +34,69 @@ type App struct {
 	name        string
 	description string
 	options     CliOptions
-	runFunc     RunFunc
-	silence     bool
+	runFunc     RunContextFunc
 	noVersion   bool
 	noConfig    bool
 	commands    []*Command
+	groups      []*cobra.Group
 	args        cobra.PositionalArgs
 	cmd         *cobra.Command
+	in          io.Reader
+	out         io.Writer
+	errOut      io.Writer
+	cfgFiles    []string
+	// viperMu guards viper, which is replaced when the configuration is
+	// reloaded.
+	viperMu sync.RWMutex
+	viper   *viper.Viper
+
+	configOptional bool
+	configPaths    []string
+
+	gracefulShutdown bool
+	shutdownTimeout  time.Duration
+	shutdownMu       sync.Mutex
+	shutdownHooks    []ShutdownHook
+
+	configWatch bool
+	// pristineOptions is a copy of the options before the configuration was
+	// unmarshalled into them, which reloads start from.
+	pristineOptions CliOptions
+	configCommands  bool
+	namedFlagSets   fname.NamedFlagSets
+	featureGate     *featuregate.FeatureGate
+	// flagSets stores the flag sections of the application and its commands.
+	flagSets   map[*cobra.Command]fname.NamedFlagSets
+	helpFormat flagvalue.Enum
+	reporter   Reporter
+	theme      Theme
+	colorMode  ColorMode
+	color      flagvalue.Enum
+	logging    *logging.Options
+	debug      *debugServerOptions
+	runnables  []Runnable
+
+	noCompletion    bool
+	flagCompletions map[string]CompletionFunc
+	argsCompletion  CompletionFunc
+
+	sensitiveKeys []string
+	// resolvedMu guards the state resolved from the last execution.
+	resolvedMu      sync.RWMutex
+	provenance      map[string]Provenance
+	flagNames       map[string]string
+	sensitiveFlags  map[string]bool
+	configFilesUsed []string
+	configOrigins   map[string]string
+	configDropInDir string
+	configContent   []byte
+	executed        atomic.Bool
 }
 
+// ErrAlreadyExecuted is returned by Execute when the application has already
+// been executed.
+var ErrAlreadyExecuted = errors.New("the application has already been executed, create a new App for every run")
+
 // Option defines optional parameters for initializing the application
 // structure.
 type Option func(*App)
@@ -93,8 +112,23 @@ func WithOptions(opt CliOptions) Option {
 // RunFunc defines the application's startup callback function.
 type RunFunc func(basename string) error
 
+// RunContextFunc defines the context-aware startup callback function shared by
+// the application and its commands. It receives the context of the execution,
+// the positional arguments and the completed options.
+type RunContextFunc func(ctx context.Context, args []string, opts CliOptions) error
+
 // WithRunFunc is used to set the application startup callback function option.
 func WithRunFunc(run RunFunc) Option {
+	return func(a *App) {
+		a.runFunc = func(context.Context, []string, CliOptions) error {
+			return run(a.basename)
+		}
+	}
+}
+
+// WithRunContextFunc is used to set the context-aware application startup
+// callback function option.
+func WithRunContextFunc(run RunContextFunc) Option {
 	return func(a *App) {
 		a.runFunc = run
 	}
@@ -109,14 +143,15 @@ func WithDescription(desc string) Option {
 
 // WithSilence sets the application to silent mode, in which the program startup
 // information, configuration information, and version information are not
-// printed in the console.
+// reported. It is the same as WithReporter(NopReporter()).
 func WithSilence() Option {
 	return func(a *App) {
-		a.silence = true
+		a.reporter = NopReporter()
 	}
 }
 
-// WithNoVersion set the application does not provide version flag.
+// WithNoVersion set the application does not provide the version flag and the
+// version command.
 func WithNoVersion() Option {
 	return func(a *App) {
 		a.noVersion = true
@@ -130,6 +165,16 @@ func WithNoConfig() Option {
 	}
 }
 
+// WithIO sets the standard input, output and error streams used by the
+// application instead of os.Stdin, os.Stdout and os.Stderr.
+func WithIO(in io.Reader, out io.Writer, errOut io.Writer) Option {
+	return func(a *App) {
+		a.in = in
+		a.out = out
+		a.errOut = errOut
+	}
+}
+
 // WithValidArgs set the validation function to valid non-flag arguments.
 func WithValidArgs(args cobra.PositionalArgs) Option {
 	return func(a *App) {
@@ -152,17 +197,36 @@ func WithDefaultValidArgs() Option {
 	}
 }
 
+// WithCommandGroups declares the groups the commands of the application are
+// listed in by the help information, see WithCommandGroupID. The built-in
+// commands are listed under "Additional Commands".
+func WithCommandGroups(groups ...*cobra.Group) Option {
+	return func(a *App) {
+		a.groups = append(a.groups, groups...)
+	}
+}
+
 // NewApp creates a new application instance based on the given application name,
 // binary name, and other options.
 func NewApp(name string, basename string, opts ...Option) *App {
 	a := &App{
-		name:     name,
-		basename: basename,
+		name:      name,
+		basename:  basename,
+		in:        os.Stdin,
+		out:       os.Stdout,
+		errOut:    os.Stderr,
+		viper:     viper.New(),
+		theme:     DefaultTheme(),
+		colorMode: ColorAuto,
 	}
 
 	for _, o := range opts {
 		o(a)
 	}
+	if a.reporter == nil {
+		// 默认以文本形式输出启动信息，进度标记的颜色由主题决定
+		a.reporter = &textReporter{w: a.out, marker: func() string { return a.progress(a.out) }}
+	}
 
 	a.buildCommand()
 
@@ -179,33 +243,61 @@ func (a *App) buildCommand() {
 		SilenceErrors: true,
 		Args:          a.args,
 	}
-	// cmd.SetUsageTemplate(usageTemplate)
-	cmd.SetOut(os.Stdout)
-	cmd.SetErr(os.Stderr)
+	cmd.SetIn(a.in)
+	cmd.SetOut(a.out)
+	cmd.SetErr(a.errOut)
 	cmd.Flags().SortFlags = true
 	// 修改flags 选项名称中的符号
 	fname.InitFlags(cmd.Flags())
 
-	if len(a.commands) > 0 {
-		for _, command := range a.commands {
-			cmd.AddCommand(command.cobraCommand())
-		}
+	cmd.AddGroup(a.groups...)
+	for _, command := range a.commands {
+		cmd.AddCommand(command.cobraCommand(a))
+	}
+	if a.configCommands && !a.noConfig {
+		cmd.AddCommand(a.configCommand())
+	}
+	hasSubCommands := cmd.HasSubCommands()
+	// 使用内置的 completion 命令代替 cobra 默认的 completion 命令
+	cmd.CompletionOptions.DisableDefaultCmd = true
+	if !a.noCompletion {
+		cmd.AddCommand(a.completionCommand())
+	}
+	cmd.AddCommand(a.docsCommand())
+	// 添加内置的 version 命令，应用自定义的同名命令优先
+	if !a.noVersion && !hasCommand(&cmd, "version") {
+		cmd.AddCommand(a.versionCommand())
+	}
+	if !hasSubCommands && cmd.Args == nil {
+		// 添加内置命令后，没有子命令的应用仍然接受任意的命令行参数
+		cmd.Args = cobra.ArbitraryArgs
+	}
+	if cmd.HasSubCommands() {
 		cmd.SetHelpCommand(helpCommand(FormatBaseName(a.basename)))
 	}
 	// 运行函数赋值
-	if a.runFunc != nil {
+	if a.runFunc != nil || len(a.runnables) > 0 {
 		cmd.RunE = a.runCommand
 	}
 
 	// 将flag Set中的pflag添加到cmd flags中
 	var namedFlagSets fname.NamedFlagSets
 	if a.options != nil {
-		namedFlagSets = a.options.Flags()
+		namedFlagSets = optionsFlags(a.options)
 		//fs := cmd.Flags()
 		fs := cmd.PersistentFlags()
 		for _, f := range namedFlagSets.FlagSets {
 			fs.AddFlagSet(f)
 		}
+		a.addSensitiveOptions(a.options, fs)
+	}
+	// 添加日志选项，作为单独的 logging 分组，同样对子命令生效
+	if a.logging != nil {
+		a.addLoggingFlags(&namedFlagSets, cmd.PersistentFlags())
+	}
+	// 添加调试服务选项，作为单独的 debug 分组，同样对子命令生效
+	if a.debug != nil {
+		a.addDebugFlags(&namedFlagSets, cmd.PersistentFlags())
 	}
 	// 检查是否设置了version选项，如果设置了则添加对应的选项参数，默认设置
 	if !a.noVersion {
@@ -213,152 +305,366 @@ func (a *App) buildCommand() {
 	}
 	// 检查是否设置了config选项，如果设置了则添加对应的选项参数，默认设置
 	if !a.noConfig {
-		addConfigFlag(a.basename, namedFlagSets.FlagSet("global"))
+		a.addConfigFlag(namedFlagSets.FlagSet("global"))
+		// 配置文件选项需要对子命令同样生效
+		cmd.PersistentFlags().AddFlag(namedFlagSets.FlagSet("global").Lookup(configFlagName))
+		cmd.PersistentPreRunE = func(cmd *cobra.Command, _ []string) error {
+			// 输出命令行说明时不需要读取配置文件
+			if a.helpFormat.Value == helpFormatJSON {
+				return nil
+			}
+			// shell 补全时通常没有配置文件，补全函数也不依赖配置
+			if cmd.Name() == cobra.ShellCompRequestCmd || cmd.Name() == cobra.ShellCompNoDescRequestCmd {
+				return nil
+			}
+
+			return a.loadConfig()
+		}
+	}
+	// 添加特性门控选项，同样对子命令生效
+	if a.featureGate != nil {
+		a.featureGate.AddFlag(namedFlagSets.FlagSet("global"))
+		cmd.PersistentFlags().AddFlag(namedFlagSets.FlagSet("global").Lookup(featuregate.FlagName))
 	}
+	// 添加帮助信息格式选项与颜色选项，同样对子命令生效
+	a.addHelpFormatFlag(namedFlagSets.FlagSet("global"))
+	cmd.PersistentFlags().AddFlag(namedFlagSets.FlagSet("global").Lookup(helpFormatFlagName))
+	a.addColorFlag(namedFlagSets.FlagSet("global"))
+	cmd.PersistentFlags().AddFlag(namedFlagSets.FlagSet("global").Lookup(colorFlagName))
 	// 配置help选项信息
 	AddGlobalFlags(namedFlagSets.FlagSet("global"), cmd.Name())
 	// add new global flagset to cmd FlagSet
 	cmd.Flags().AddFlagSet(namedFlagSets.FlagSet("global"))
-	// 设置自定义使用信息和帮助信息
-	if a.commands == nil {
-		addCmdTemplate(&cmd, namedFlagSets)
-	}
+	// 设置自定义使用信息和帮助信息，子命令同样使用分组的帮助信息
+	a.setHelpFunc(&cmd)
+	registerCompletions(&cmd, a.options, a.flagCompletions, a.argsCompletion)
 
+	a.namedFlagSets = namedFlagSets
+	a.registerFlagSets(&cmd, namedFlagSets)
 	a.cmd = &cmd
 }
 
 // Run is used to launch the application.
 func (a *App) Run() {
-	if err := a.cmd.Execute(); err != nil {
-		fmt.Printf("%v %v\n", color.RedString("Error:"), err)
-		os.Exit(1)
-	}
+	a.RunContext(context.Background())
 }
 
 // RunContext is used to launch the application with context.
 func (a *App) RunContext(ctx context.Context) {
-	if err := a.cmd.ExecuteContext(ctx); err != nil {
-		fmt.Printf("%v %v\n", color.RedString("Error:"), err)
-		os.Exit(1)
+	code, err := a.Execute(ctx, os.Args[1:])
+	if err != nil {
+		a.printError(a.errOut, err)
+	}
+	if code != 0 {
+		os.Exit(code)
 	}
 }
 
+// Execute runs the application with the given command line arguments, which
+// must not include the program name, and returns the exit code together with
+// the error, if any. Unlike Run, Execute never terminates the process, so the
+// application can be embedded in a larger program or driven from tests.
+//
+// An App runs once: the parsed flags are bound to the options, so a second
+// call returns ErrAlreadyExecuted. Create a new App for every run instead.
+//
+// With WithGracefulShutdown, a second shutdown signal makes Execute return a
+// *ForcedShutdownError right away, while the run function and the shutdown
+// hooks may still be running.
+func (a *App) Execute(ctx context.Context, args []string) (int, error) {
+	if !a.executed.CompareAndSwap(false, true) {
+		return 1, ErrAlreadyExecuted
+	}
+	if args == nil {
+		args = []string{}
+	}
+	a.cmd.SetArgs(args)
+
+	var err error
+	if a.gracefulShutdown {
+		var signals *signalHandler
+		ctx, signals = newSignalHandler(ctx)
+		defer signals.stop()
+
+		// 在单独的 goroutine 中执行，以便收到第二个信号时立即返回
+		done := make(chan error, 1)
+		go func() {
+			done <- a.execute(ctx, signals)
+		}()
+		select {
+		case err = <-done:
+		case sig := <-signals.forced:
+			err = &ForcedShutdownError{Signal: sig}
+		}
+	} else {
+		err = a.execute(ctx, nil)
+	}
+	if err != nil {
+		return exitCode(err), err
+	}
+
+	return 0, nil
+}
+
+// execute executes the command and calls the shutdown hooks.
+func (a *App) execute(ctx context.Context, signals *signalHandler) error {
+	err := a.cmd.ExecuteContext(ctx)
+	if signals != nil && signals.signaled.Load() && errors.Is(err, context.Canceled) {
+		// the run function returned because of the shutdown signal
+		err = nil
+	}
+	if shutdownErr := a.shutdown(); shutdownErr != nil {
+		err = errors.NewAggregate([]error{err, shutdownErr})
+	}
+
+	return err
+}
+
+// printError prints err to w. The errors of an aggregate, such as the
+// validation errors of the options, are printed one per line.
+func (a *App) printError(w io.Writer, err error) {
+	prefix := a.paint(w, a.theme.Error, "Error:")
+	var agg errors.Aggregate
+	if errors.As(err, &agg) {
+		if errs := errors.Flatten(agg).Errors(); len(errs) > 1 {
+			fmt.Fprintf(w, "%v\n", prefix)
+			for _, e := range errs {
+				fmt.Fprintf(w, "  - %v\n", e)
+			}
+
+			return
+		}
+	}
+	fmt.Fprintf(w, "%v %v\n", prefix, err)
+}
+
+// exitCode returns the exit code carried by err, falling back to 1 when err
+// does not implement ExitCode() int.
+func exitCode(err error) int {
+	var coder interface{ ExitCode() int }
+	if errors.As(err, &coder) && coder.ExitCode() != 0 {
+		return coder.ExitCode()
+	}
+
+	return 1
+}
+
 // Command returns cobra command instance inside the application.
 func (a *App) Command() *cobra.Command {
 	return a.cmd
 }
 
+// Viper returns the viper instance owned by the application, which holds the
+// configuration merged from the config file, environment and flags. The
+// instance is replaced when the configuration is reloaded, see
+// WithConfigWatch, so call Viper again instead of keeping it.
+func (a *App) Viper() *viper.Viper {
+	a.viperMu.RLock()
+	defer a.viperMu.RUnlock()
+
+	return a.viper
+}
+
+// setViper replaces the viper instance of the application.
+func (a *App) setViper(v *viper.Viper) {
+	a.viperMu.Lock()
+	a.viper = v
+	a.viperMu.Unlock()
+}
+
 func (a *App) runCommand(cmd *cobra.Command, args []string) error {
+	if a.printSpecIfRequested(a.out) {
+		return nil
+	}
 	if !a.noVersion {
 		// display application version information
-		verflag.PrintAndExitIfRequested()
-	}
-
-	var pb strings.Builder // 记录viper映射config前的flags建值
-	//var afterConfig []string
-	if !a.silence { // config配置必须在bind flags之前打印
-		if !a.noConfig {
-			fmt.Printf("%v Config file used: `%s`\n", progressMessage, viper.ConfigFileUsed())
-			afterConfig := viper.AllKeys() // 配置文件的建值
-			printConfig(afterConfig)
+		if verflag.PrintIfRequested(cmd.Flags(), a.out) {
+			return nil
 		}
-
 	}
 
+	var flags []FlagItem // 记录viper映射config前的flags建值
 	if !a.noConfig {
-		//afterConfig = viper.AllKeys() // 配置文件的建值
-
-		pbF := func(flag *pflag.Flag) {
-			//pb.WriteString(fmt.Sprintf("FLAG: --%s=%q\n", flag.Name, flag.Value))
+		cmd.Flags().VisitAll(func(flag *pflag.Flag) {
 			if flag.Changed || flag.Value.String() != flag.DefValue {
-				// 只有在 flag 被设置，或者值与默认值不同的情况下才调用 pb.WriteString
-				pb.WriteString(fmt.Sprintf("FLAG: --%s=%q\n", flag.Name, flag.Value))
+				// 只有在 flag 被设置，或者值与默认值不同的情况下才记录
+				flags = append(flags, FlagItem{Name: flag.Name, Value: fname.FlagValue(flag)})
 			}
-		}
-		cmd.Flags().VisitAll(pbF)
+		})
+	}
 
-		if err := viper.BindPFlags(cmd.Flags()); err != nil {
+	if err := a.bindOptions(cmd.Flags(), a.options); err != nil {
+		return err
+	}
+	if err := a.checkConstraints(a.namedFlagSets, cmd.Flags()); err != nil {
+		return err
+	}
+
+	if !a.noConfig {
+		files := a.ConfigFilesUsed()
+		a.report(EventConfigFiles, files, "Config file used: `%s`", strings.Join(files, ", "))
+		if items := a.sortedProvenance(); len(items) > 0 {
+			a.report(EventConfigItems, redactedProvenance(items), "Configuration items:")
+		}
+	}
+	wd, _ := os.Getwd()
+	a.report(EventWorkingDir, wd, "WorkingDir: %s", wd)
+	a.report(EventFlags, flags, "Flags items:")
+	a.report(EventStarting, a.name, "Starting %s ...", a.name)
+	if !a.noVersion {
+		info := version.Get()
+		a.report(EventVersion, info, "Version: `%s`", info.ToJSON())
+	}
+	if a.featureGate != nil {
+		a.reportFeatureGates(a.featureGate)
+	}
+	if a.options != nil {
+		if err := a.applyOptionRules(a.options); err != nil {
 			return err
 		}
+	}
+	// run application
+	if len(a.runnables) > 0 {
+		// 运行函数与各组件并发运行
+		return a.callRunFunc(cmd, a.runnablesFunc(a.runFunc), args, a.options)
+	}
+	if a.runFunc != nil {
+		return a.callRunFunc(cmd, a.runFunc, args, a.options)
+	}
+
+	return nil
+}
 
-		if err := viper.Unmarshal(a.options); err != nil {
+// bindOptions merges the parsed flags in fs with the configuration file and
+// environment variables and unmarshals the result into opts. The feature gates
+// are set from the configuration file and environment variables as well.
+func (a *App) bindOptions(fs *pflag.FlagSet, opts CliOptions) error {
+	if !a.noConfig {
+		if err := a.Viper().BindPFlags(fs); err != nil {
+			return err
+		}
+		a.resolveProvenance(fs)
+		if err := a.applyFeatureGates(fs); err != nil {
 			return err
 		}
+
+		if opts != nil {
+			if a.configWatch {
+				// 记录读取配置之前的 options，重新加载配置时以它为基础，使删除的配置项恢复为默认值
+				a.pristineOptions, _ = cloneOptions(opts)
+			}
+			if err := a.Viper().Unmarshal(opts, decoderConfig(opts)); err != nil {
+				return err
+			}
+		}
 	}
+	// 在 Complete 和 Validate 之前配置默认的 slog 日志
+	if err := a.applyLogging(); err != nil {
+		return err
+	}
+	if err := a.bindDebugOptions(); err != nil {
+		return err
+	}
+	a.collectSensitiveFlags(fs)
 
-	if !a.silence {
-		printWorkingDir()
-		fmt.Printf("%v Flags items:\n", progressMessage)
-		fmt.Printf(pb.String())
+	flagNames := map[string]string{}
+	fs.VisitAll(func(flag *pflag.Flag) {
+		flagNames[strings.ToLower(flag.Name)] = flag.Name
+	})
+	a.resolvedMu.Lock()
+	a.flagNames = flagNames
+	a.resolvedMu.Unlock()
 
-		fmt.Printf("%v Starting %s ...\n", progressMessage, a.name)
-		if !a.noVersion {
-			fmt.Printf("%v Version: `%s`\n", progressMessage, version.Get().ToJSON())
-		}
-		//if !a.noConfig {
-		//	fmt.Printf("%v Config file used: `%s`\n", progressMessage, viper.ConfigFileUsed())
-		//	printConfig(afterConfig)
-		//}
+	return nil
+}
+
+// checkConstraints checks the constraints declared on fss. A flag counts as
+// set if its value comes from the command line, the environment or the
+// configuration file, so it must be called after bindOptions.
+func (a *App) checkConstraints(fss fname.NamedFlagSets, fs *pflag.FlagSet) error {
+	if len(fss.Constraints) == 0 {
+		return nil
 	}
-	if a.options != nil {
-		if err := a.applyOptionRules(); err != nil {
-			return err
+
+	provenance := a.Provenance()
+	isSet := func(name string) bool {
+		if item, ok := provenance[strings.ToLower(name)]; ok && !a.noConfig {
+			return item.Source != SourceDefault
 		}
+		flag := fs.Lookup(name)
+
+		return flag != nil && flag.Changed
 	}
-	// run application
-	if a.runFunc != nil {
-		return a.runFunc(a.basename)
+
+	return errors.NewAggregate(fss.Check(isSet))
+}
+
+// flagName returns the command line flag bound to the configuration key, or
+// an empty string if there is none.
+func (a *App) flagName(key string) string {
+	a.resolvedMu.RLock()
+	defer a.resolvedMu.RUnlock()
+
+	if name, ok := a.flagNames[key]; ok {
+		return "--" + name
+	}
+
+	return ""
+}
+
+func (a *App) applyOptionRules(opts CliOptions) error {
+	if err := a.completeOptions(opts); err != nil {
+		return err
+	}
+	// 检查 opts 是否实现了 PrintableOptions 接口。
+	if _, ok := opts.(PrintableOptions); ok {
+		// 如果实现了 PrintableOptions 接口，那么就报告 options 的配置信息，静默模式下不输出。
+		// 从敏感字段已替换的副本输出，而不是在输出中替换敏感值
+		config := a.redactedOptions(opts).(PrintableOptions).String()
+		a.report(EventOptions, config, "Config: `%s`", config)
 	}
 
 	return nil
 }
 
-func (a *App) applyOptionRules() error {
-	// 首先检查 a.options 是否实现了 CompletableOptions 接口。
+// completeOptions completes and validates opts. The validate struct tags of
+// opts are checked before the Validate method is called, and the errors of
+// both are aggregated.
+func (a *App) completeOptions(opts CliOptions) error {
+	// 首先检查 opts 是否实现了 CompletableOptions 接口。
 	// Go语言中，接口的实现是隐式的，我们可以通过类型断言来判断某个变量是否实现了某个接口。
-	if CompletableOption, ok := a.options.(CompletableOptions); ok {
-		// 如果 a.options 实现了 CompletableOptions 接口，那么就调用这个接口的 Complete 方法。
+	if CompletableOption, ok := opts.(CompletableOptions); ok {
+		// 如果 opts 实现了 CompletableOptions 接口，那么就调用这个接口的 Complete 方法。
 		// 完成之后，检查是否有错误发生，如果有错误，那么就直接返回这个错误。
 		if err := CompletableOption.Complete(); err != nil {
 			return err
 		}
 	}
-	// 调用 a.options 的 Validate 方法，该方法返回一个包含所有错误的切片。
-	// 如果返回的错误切片的长度不为0，表明验证过程中出现了错误，那么创建一个新的错误聚合并返回。
-	if errs := a.options.Validate(); len(errs) != 0 {
+	// 先根据结构体字段的 validate 标签校验，再调用 opts 的 Validate 方法，两者返回的错误合并在一起。
+	// 如果错误切片的长度不为0，表明验证过程中出现了错误，那么创建一个新的错误聚合并返回。
+	errs := a.validator(opts).Struct(opts)
+	errs = append(errs, opts.Validate()...)
+	if len(errs) != 0 {
 		return errors.NewAggregate(errs)
 	}
-	// 检查 a.options 是否实现了 PrintableOptions 接口，并且 App 是否设置为禁声模式（a.silence 不为 true）。
-	if printableOptions, ok := a.options.(PrintableOptions); ok && !a.silence {
-		// 如果实现了 PrintableOptions 接口且 App 没有被设置为禁声模式，
-		// 那么就打印 options 的配置信息。这里假设 progressMessage 是个已定义的全局变量。
-		fmt.Printf("%v Config: `%s`\n", progressMessage, printableOptions.String())
-	}
 
 	return nil
 }
 
-func printWorkingDir() {
-	wd, _ := os.Getwd()
-	fmt.Printf("%v WorkingDir: %s\n", progressMessage, wd)
-}
-
-// 自定义格式的使用和帮助信息
-func addCmdTemplate(cmd *cobra.Command, namedFlagSets fname.NamedFlagSets) {
-	usageFmt := "Usage:\n  %s\n"
-	cols, _, _ := TerminalSize(cmd.OutOrStdout())
-	// 设置命令的使用信息打印函数
-	cmd.SetUsageFunc(func(cmd *cobra.Command) error {
-		fmt.Fprintf(cmd.OutOrStderr(), usageFmt, cmd.UseLine())
-		fname.PrintSections(cmd.OutOrStderr(), namedFlagSets, cols)
+// validator returns the validator of the struct tags of opts, which reports
+// the configuration key, flag and environment variable of invalid fields.
+func (a *App) validator(opts CliOptions) *validation.Validator {
+	tagName := optionsTagName(opts)
+	v := &validation.Validator{
+		TagName:        tagName,
+		SquashEmbedded: tagName == fname.FlagTag,
+		FlagName:       a.flagName,
+	}
+	if !a.noConfig {
+		v.EnvName = a.envVarName
+	}
 
-		return nil
-	})
-	// 设置命令的帮助信息打印函数
-	cmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
-		fmt.Fprintf(cmd.OutOrStdout(), "%s\n\n"+usageFmt, cmd.Long, cmd.UseLine())
-		fname.PrintSections(cmd.OutOrStdout(), namedFlagSets, cols)
-	})
+	return v
 }
 
 func TerminalSize(w io.Writer) (int, int, error) {
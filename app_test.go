@@ -0,0 +1,46 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/marmotedu/errors"
+)
+
+type testOptions struct {
+	Name string `flag:"name" default:"default" usage:"Name."`
+}
+
+func (o *testOptions) Validate() []error { return nil }
+
+func TestExecuteTwice(t *testing.T) {
+	opts := &testOptions{}
+	runs := 0
+	a := NewApp("test", "test", WithNoConfig(), WithSilence(), WithOptions(opts),
+		WithIO(nil, io.Discard, io.Discard),
+		WithRunContextFunc(func(context.Context, []string, CliOptions) error {
+			runs++
+
+			return nil
+		}))
+
+	if code, err := a.Execute(context.Background(), []string{"--name=first"}); code != 0 || err != nil {
+		t.Fatalf("first Execute() = %d, %v, want 0, nil", code, err)
+	}
+	if opts.Name != "first" {
+		t.Errorf("Name = %q after the first run, want %q", opts.Name, "first")
+	}
+
+	code, err := a.Execute(context.Background(), nil)
+	if code != 1 || !errors.Is(err, ErrAlreadyExecuted) {
+		t.Errorf("second Execute() = %d, %v, want 1, %v", code, err, ErrAlreadyExecuted)
+	}
+	if runs != 1 {
+		t.Errorf("run function called %d times, want 1", runs)
+	}
+}
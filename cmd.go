@@ -5,13 +5,12 @@
 package app
 
 import (
-	"fmt"
-	"os"
+	"context"
 	"runtime"
 	"strings"
 
-	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+	"github.com/yuanbaopig/app/fname"
 )
 
 // Command is a sub command structure of a cli application.
@@ -22,7 +21,12 @@ type Command struct {
 	desc     string
 	options  CliOptions
 	commands []*Command
-	runFunc  RunCommandFunc
+	runFunc  RunContextFunc
+	groupID  string
+	groups   []*cobra.Group
+
+	flagCompletions map[string]CompletionFunc
+	argsCompletion  CompletionFunc
 }
 
 // CommandOption defines optional parameters for initializing the command
@@ -37,12 +41,38 @@ func WithCommandOptions(opt CliOptions) CommandOption {
 	}
 }
 
+// WithCommandGroupID lists the command in the help information of its parent
+// under the group with the given id, which must be declared on the parent.
+func WithCommandGroupID(id string) CommandOption {
+	return func(c *Command) {
+		c.groupID = id
+	}
+}
+
+// WithCommandSubGroups declares the groups the sub commands of the command are
+// listed in by the help information.
+func WithCommandSubGroups(groups ...*cobra.Group) CommandOption {
+	return func(c *Command) {
+		c.groups = append(c.groups, groups...)
+	}
+}
+
 // RunCommandFunc defines the application's command startup callback function.
 type RunCommandFunc func(args []string) error
 
 // WithCommandRunFunc is used to set the application's command startup callback
 // function option.
 func WithCommandRunFunc(run RunCommandFunc) CommandOption {
+	return func(c *Command) {
+		c.runFunc = func(_ context.Context, args []string, _ CliOptions) error {
+			return run(args)
+		}
+	}
+}
+
+// WithCommandRunContextFunc is used to set the application's context-aware
+// command startup callback function option.
+func WithCommandRunContextFunc(run RunContextFunc) CommandOption {
 	return func(c *Command) {
 		c.runFunc = run
 	}
@@ -73,38 +103,64 @@ func (c *Command) AddCommands(cmds ...*Command) {
 	c.commands = append(c.commands, cmds...)
 }
 
-func (c *Command) cobraCommand() *cobra.Command {
+func (c *Command) cobraCommand(a *App) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   c.usage,
-		Short: c.desc,
+		Use:     c.usage,
+		Short:   c.desc,
+		GroupID: c.groupID,
 	}
-	cmd.SetOut(os.Stdout)
+	cmd.AddGroup(c.groups...)
 	cmd.Flags().SortFlags = false
 	if len(c.commands) > 0 {
 		for _, command := range c.commands {
-			cmd.AddCommand(command.cobraCommand())
+			cmd.AddCommand(command.cobraCommand(a))
 		}
 	}
-	if c.runFunc != nil {
-		cmd.Run = c.runCommand
-	}
+	// 每次构建命令都使用新的选项分组，同一个 Command 可以在多个应用中复用
+	var namedFlagSets fname.NamedFlagSets
 	if c.options != nil {
-		for _, f := range c.options.Flags().FlagSets {
+		namedFlagSets = optionsFlags(c.options)
+		for _, f := range namedFlagSets.FlagSets {
 			cmd.Flags().AddFlagSet(f)
 		}
+		a.addSensitiveOptions(c.options, cmd.Flags())
 		// c.options.AddFlags(cmd.Flags())
 	}
-	addHelpCommandFlag(c.usage, cmd.Flags())
+	addHelpCommandFlag(c.usage, namedFlagSets.FlagSet("global"))
+	cmd.Flags().AddFlagSet(namedFlagSets.FlagSet("global"))
+	if c.runFunc != nil {
+		cmd.RunE = c.runCommand(a, namedFlagSets)
+	}
+	registerCompletions(cmd, c.options, c.flagCompletions, c.argsCompletion)
+	a.registerFlagSets(cmd, namedFlagSets)
 
 	return cmd
 }
 
-func (c *Command) runCommand(cmd *cobra.Command, args []string) {
-	if c.runFunc != nil {
-		if err := c.runFunc(args); err != nil {
-			fmt.Printf("%v %v\n", color.RedString("Error:"), err)
-			os.Exit(1)
+// runCommand returns the cobra run function of the command. The options of the
+// command go through the same lifecycle as the options of the application:
+// flags, then configuration file and environment variables, then Complete,
+// Validate and the optional print. The constraints declared on fss are checked
+// after the configuration is bound.
+func (c *Command) runCommand(a *App, fss fname.NamedFlagSets) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if a.printSpecIfRequested(a.out) {
+			return nil
+		}
+		if err := a.bindOptions(cmd.Flags(), c.options); err != nil {
+			return err
+		}
+		if err := a.checkConstraints(fss, cmd.Flags()); err != nil {
+			return err
 		}
+
+		if c.options != nil {
+			if err := a.applyOptionRules(c.options); err != nil {
+				return err
+			}
+		}
+
+		return a.callRunFunc(cmd, c.runFunc, args, c.options)
 	}
 }
 
@@ -0,0 +1,136 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/moby/term"
+	"github.com/spf13/pflag"
+	"github.com/yuanbaopig/app/flagvalue"
+)
+
+const colorFlagName = "color"
+
+// ColorMode controls whether the output of the application is colored.
+type ColorMode string
+
+// Define the color modes.
+const (
+	// ColorAuto colors the output written to a terminal, unless the NO_COLOR
+	// environment variable is set or TERM is dumb.
+	ColorAuto ColorMode = "auto"
+	// ColorAlways always colors the output.
+	ColorAlways ColorMode = "always"
+	// ColorNever never colors the output.
+	ColorNever ColorMode = "never"
+)
+
+// flagUsageName matches the names of a flag at the beginning of a line of
+// pflag usages, e.g. "  -c, --config".
+var flagUsageName = regexp.MustCompile(`(?m)^  (-\S, |    )--[^\s=\[]+`)
+
+// Theme controls the colors of the output of the application. A nil color
+// leaves the text plain.
+type Theme struct {
+	// Heading colors the headings of the help information, e.g. "Usage:".
+	Heading *color.Color
+	// Command colors the usage lines and the names of the commands.
+	Command *color.Color
+	// Flag colors the names of the flags in the help information.
+	Flag *color.Color
+	// Error colors the "Error:" prefix of the errors.
+	Error *color.Color
+	// Progress colors the "==>" marker of the startup information.
+	Progress *color.Color
+}
+
+// DefaultTheme returns the theme used unless WithTheme is given.
+func DefaultTheme() Theme {
+	return Theme{
+		Heading:  color.New(color.FgCyan),
+		Command:  color.New(color.FgGreen),
+		Flag:     color.New(color.FgGreen),
+		Error:    color.New(color.FgRed),
+		Progress: color.New(color.FgGreen),
+	}
+}
+
+// WithTheme sets the colors of the output of the application.
+func WithTheme(theme Theme) Option {
+	return func(a *App) {
+		a.theme = theme
+	}
+}
+
+// WithColor sets the default color mode of the application, which can be
+// overridden by the color flag.
+func WithColor(mode ColorMode) Option {
+	return func(a *App) {
+		a.colorMode = mode
+	}
+}
+
+// addColorFlag adds the color flag, which controls whether the output is
+// colored, to fs.
+func (a *App) addColorFlag(fs *pflag.FlagSet) {
+	a.color = flagvalue.NewEnum(string(a.colorMode), string(ColorAuto), string(ColorAlways), string(ColorNever))
+	fs.Var(&a.color, colorFlagName, "Whether to color the output. "+
+		"The auto mode colors the output written to a terminal unless NO_COLOR is set or TERM is dumb.")
+}
+
+// colorEnabled reports whether the output written to w is colored.
+func (a *App) colorEnabled(w io.Writer) bool {
+	switch ColorMode(a.color.Value) {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	_, isTerminal := term.GetFdInfo(w)
+
+	return isTerminal
+}
+
+// paint formats according to the format specifier, in color c if the output
+// written to w is colored.
+func (a *App) paint(w io.Writer, c *color.Color, format string, args ...interface{}) string {
+	if c == nil || !a.colorEnabled(w) {
+		return fmt.Sprintf(format, args...)
+	}
+
+	// 复制颜色后强制开启，不受 fatih/color 全局设置的影响
+	painted := *c
+	painted.EnableColor()
+
+	return painted.Sprintf(format, args...)
+}
+
+// progress returns the marker of the startup information written to w.
+func (a *App) progress(w io.Writer) string {
+	return a.paint(w, a.theme.Progress, "==>")
+}
+
+// paintFlags colors the names of the flags in the pflag usages s.
+func (a *App) paintFlags(w io.Writer, s string) string {
+	if a.theme.Flag == nil || !a.colorEnabled(w) {
+		return s
+	}
+
+	return flagUsageName.ReplaceAllStringFunc(s, func(name string) string {
+		names := strings.TrimLeft(name, " ")
+
+		return name[:len(name)-len(names)] + a.paint(w, a.theme.Flag, "%s", names)
+	})
+}
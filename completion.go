@@ -0,0 +1,170 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// CompletionFunc returns the completion candidates for the value being
+// completed, given the positional arguments already on the command line.
+type CompletionFunc func(args []string, toComplete string) ([]string, cobra.ShellCompDirective)
+
+// CompletableFlags abstracts options which complete the values of their
+// flags dynamically, e.g. by listing the configuration profiles.
+type CompletableFlags interface {
+	// FlagCompletions returns the completion functions indexed by flag name.
+	FlagCompletions() map[string]CompletionFunc
+}
+
+// CompletableArgs abstracts options which complete the positional arguments
+// of their command dynamically.
+type CompletableArgs interface {
+	CompleteArgs(args []string, toComplete string) ([]string, cobra.ShellCompDirective)
+}
+
+// enumValue is implemented by flag values with a fixed set of allowed values,
+// such as flagvalue.Enum, which are completed automatically.
+type enumValue interface {
+	AllowedValues() []string
+}
+
+// WithNoCompletion set the application does not provide the completion
+// command.
+func WithNoCompletion() Option {
+	return func(a *App) {
+		a.noCompletion = true
+	}
+}
+
+// WithFlagCompletion sets the completion function of the value of the named
+// flag of the application.
+func WithFlagCompletion(name string, fn CompletionFunc) Option {
+	return func(a *App) {
+		if a.flagCompletions == nil {
+			a.flagCompletions = map[string]CompletionFunc{}
+		}
+		a.flagCompletions[name] = fn
+	}
+}
+
+// WithArgsCompletion sets the completion function of the positional arguments
+// of the application.
+func WithArgsCompletion(fn CompletionFunc) Option {
+	return func(a *App) {
+		a.argsCompletion = fn
+	}
+}
+
+// WithCommandFlagCompletion sets the completion function of the value of the
+// named flag of the command.
+func WithCommandFlagCompletion(name string, fn CompletionFunc) CommandOption {
+	return func(c *Command) {
+		if c.flagCompletions == nil {
+			c.flagCompletions = map[string]CompletionFunc{}
+		}
+		c.flagCompletions[name] = fn
+	}
+}
+
+// WithCommandArgsCompletion sets the completion function of the positional
+// arguments of the command.
+func WithCommandArgsCompletion(fn CompletionFunc) CommandOption {
+	return func(c *Command) {
+		c.argsCompletion = fn
+	}
+}
+
+func (a *App) completionCommand() *cobra.Command {
+	name := FormatBaseName(a.basename)
+
+	return &cobra.Command{
+		Use:   "completion [bash|zsh|fish|powershell]",
+		Short: "Generate the autocompletion script for the specified shell.",
+		Long: fmt.Sprintf(`Generate the autocompletion script for %[1]s for the specified shell.
+
+To load completions in the current shell session:
+
+  bash:       source <(%[1]s completion bash)
+  zsh:        source <(%[1]s completion zsh)
+  fish:       %[1]s completion fish | source
+  powershell: %[1]s completion powershell | Out-String | Invoke-Expression
+`, name),
+		ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+		Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		DisableFlagsInUseLine: true,
+		// completion scripts do not need the configuration file
+		PersistentPreRunE: func(*cobra.Command, []string) error {
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root, out := cmd.Root(), cmd.OutOrStdout()
+			switch args[0] {
+			case "bash":
+				return root.GenBashCompletionV2(out, true)
+			case "zsh":
+				return root.GenZshCompletion(out)
+			case "fish":
+				return root.GenFishCompletion(out, true)
+			default:
+				return root.GenPowerShellCompletionWithDesc(out)
+			}
+		},
+	}
+}
+
+// registerCompletions registers the completion functions of the flags and
+// the positional arguments of cmd: the given functions, then those of opts
+// if it implements CompletableFlags or CompletableArgs, then the allowed
+// values of enum flags and file names for the config flag.
+func registerCompletions(cmd *cobra.Command, opts CliOptions, flags map[string]CompletionFunc, complete CompletionFunc) {
+	if completable, ok := opts.(CompletableFlags); ok {
+		for name, fn := range completable.FlagCompletions() {
+			if _, ok := flags[name]; !ok {
+				registerFlagCompletion(cmd, name, fn)
+			}
+		}
+	}
+	for name, fn := range flags {
+		registerFlagCompletion(cmd, name, fn)
+	}
+	if completable, ok := opts.(CompletableArgs); ok && complete == nil {
+		complete = completable.CompleteArgs
+	}
+	if complete != nil {
+		cmd.ValidArgsFunction = func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return complete(args, toComplete)
+		}
+	}
+
+	visit := func(flag *pflag.Flag) {
+		if _, ok := cmd.GetFlagCompletionFunc(flag.Name); ok {
+			return
+		}
+		switch value := flag.Value.(type) {
+		case enumValue:
+			allowed := value.AllowedValues()
+			_ = cmd.RegisterFlagCompletionFunc(flag.Name, func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+				return allowed, cobra.ShellCompDirectiveNoFileComp
+			})
+		default:
+			if flag.Name == configFlagName {
+				_ = cmd.MarkFlagFilename(configFlagName, viper.SupportedExts...)
+			}
+		}
+	}
+	cmd.Flags().VisitAll(visit)
+	cmd.PersistentFlags().VisitAll(visit)
+}
+
+func registerFlagCompletion(cmd *cobra.Command, name string, fn CompletionFunc) {
+	_ = cmd.RegisterFlagCompletionFunc(name, func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return fn(args, toComplete)
+	})
+}
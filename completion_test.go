@@ -0,0 +1,35 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestCompletionWithoutConfigFile(t *testing.T) {
+	for _, cmd := range []string{cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd} {
+		out := &bytes.Buffer{}
+		// 在没有配置文件的目录中查找配置文件
+		a := NewApp("test", "test", WithOptions(&testOptions{}), WithConfigPaths(t.TempDir()), WithIO(nil, out, io.Discard),
+			WithFlagCompletion("name", func([]string, string) ([]string, cobra.ShellCompDirective) {
+				return []string{"alpha", "beta"}, cobra.ShellCompDirectiveNoFileComp
+			}),
+			WithRunContextFunc(func(context.Context, []string, CliOptions) error {
+				return nil
+			}))
+		if code, err := a.Execute(context.Background(), []string{cmd, "--name", ""}); code != 0 || err != nil {
+			t.Fatalf("%s: Execute() = %d, %v, want 0, nil", cmd, code, err)
+		}
+		if !strings.Contains(out.String(), "alpha") || !strings.Contains(out.String(), "beta") {
+			t.Errorf("%s: completions = %q, want alpha and beta", cmd, out.String())
+		}
+	}
+}
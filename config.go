@@ -5,73 +5,295 @@
 package app
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
-	"github.com/gosuri/uitable"
-	"github.com/spf13/cobra"
+	"github.com/mitchellh/go-homedir"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
 )
 
 const configFlagName = "config"
 
-var cfgFile string
-
-// nolint: gochecknoinits
-func init() {
-	pflag.StringVarP(&cfgFile, "config", "c", cfgFile, "Read configuration from specified `FILE`, "+
-		"support JSON, TOML, YAML, HCL, or Java properties formats.")
-}
+// envKeyReplacer maps configuration keys to environment variable names.
+var envKeyReplacer = strings.NewReplacer(".", "_", "-", "_")
 
 // addConfigFlag adds flags for a specific server to the specified FlagSet
 // object.
-func addConfigFlag(basename string, fs *pflag.FlagSet) {
+func (a *App) addConfigFlag(fs *pflag.FlagSet) {
 	// 向指定的标志集合中添加配置文件标志。这个标志通常用于指定配置文件的路径
-	fs.AddFlag(pflag.Lookup(configFlagName))
+	fs.StringArrayVarP(&a.cfgFiles, configFlagName, "c", a.cfgFiles, "Read configuration from specified `FILE`, "+
+		"support JSON, TOML, YAML, HCL, or Java properties formats. "+
+		"May be repeated, later files override earlier ones.")
+	a.configureEnv(a.viper)
+}
+
+// configureEnv makes v read the environment variables of the application.
+func (a *App) configureEnv(v *viper.Viper) {
 	// 自动读取环境变量
-	viper.AutomaticEnv()
+	v.AutomaticEnv()
 	// 设置环境变量前缀，基于应用名称的大写形式
-	viper.SetEnvPrefix(strings.Replace(strings.ToUpper(basename), "-", "_", -1))
+	v.SetEnvPrefix(a.envPrefix())
 	// 设置环境变量键的替换规则，将 "." 和 "-" 替换为 "_"
-	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_", "-", "_"))
+	v.SetEnvKeyReplacer(envKeyReplacer)
+}
 
-	// 在 Cobra 命令初始化时执行的回调函数。
-	cobra.OnInitialize(func() {
-		if cfgFile != "" {
-			viper.SetConfigFile(cfgFile)
-		} else {
-			viper.AddConfigPath(".")
+// envPrefix returns the prefix of the environment variables read by the
+// application, which is the upper-case basename.
+func (a *App) envPrefix() string {
+	return strings.Replace(strings.ToUpper(a.basename), "-", "_", -1)
+}
+
+// envVarName returns the name of the environment variable which is bound to
+// the given configuration key.
+func (a *App) envVarName(key string) string {
+	return envKeyReplacer.Replace(strings.ToUpper(a.envPrefix() + "_" + key))
+}
+
+// WithConfigOptional makes the configuration file optional: the application
+// starts with flags, environment variables and defaults only if no
+// configuration file is found in the search paths. Files given with the config
+// flag must still exist.
+func WithConfigOptional() Option {
+	return func(a *App) {
+		a.configOptional = true
+	}
+}
+
+// WithConfigPaths sets the directories searched for a configuration file named
+// after the basename when no file is given with the config flag. Environment
+// variables and a leading "~" are expanded. By default the current directory,
+// XDGConfigDir, HomeConfigDir and /etc/<prefix> are searched, where prefix is
+// the part of the basename before the first "-".
+func WithConfigPaths(paths ...string) Option {
+	return func(a *App) {
+		a.configPaths = paths
+	}
+}
+
+// XDGConfigDir returns the configuration directory of the named application
+// according to the XDG Base Directory Specification, which is
+// $XDG_CONFIG_HOME/<name>, or ~/.config/<name> if XDG_CONFIG_HOME is not set.
+func XDGConfigDir(name string) string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, name)
+	}
+
+	return filepath.Join("~", ".config", name)
+}
+
+// HomeConfigDir returns the configuration directory of the named application
+// in the home directory, which is ~/.<name>.
+func HomeConfigDir(name string) string {
+	return filepath.Join("~", "."+name)
+}
 
-			if names := strings.Split(basename, "-"); len(names) > 1 {
-				//viper.AddConfigPath(filepath.Join(homedir.HomeDir(), "."+names[0]))
-				viper.AddConfigPath(filepath.Join("/etc", names[0])) // 如果应用名称为"db-apiserver"，则会增加一个 /etc/db 路径
+// ConfigFilesUsed returns the configuration files loaded by the last execution
+// of the application, in the order they were merged.
+func (a *App) ConfigFilesUsed() []string {
+	a.resolvedMu.RLock()
+	defer a.resolvedMu.RUnlock()
+
+	return append([]string(nil), a.configFilesUsed...)
+}
+
+// searchPaths returns the expanded directories searched for a configuration
+// file.
+func (a *App) searchPaths() []string {
+	paths := a.configPaths
+	if paths == nil {
+		paths = []string{".", XDGConfigDir(a.basename), HomeConfigDir(a.basename)}
+		if names := strings.Split(a.basename, "-"); len(names) > 1 {
+			paths = append(paths, filepath.Join("/etc", names[0])) // 如果应用名称为"db-apiserver"，则会增加一个 /etc/db 路径
+		}
+	}
+
+	expanded := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if path, err := homedir.Expand(os.ExpandEnv(path)); err == nil {
+			expanded = append(expanded, path)
+		}
+	}
+
+	return expanded
+}
+
+// configFiles returns the configuration files to load, in merge order: the
+// files given with the config flag, or the first file named after the basename
+// found in the search paths, followed by the files of the <basename>.d drop-in
+// directory in lexical order. The drop-in directory is looked up next to the
+// first configuration file, or in the search paths if there is none.
+func (a *App) configFiles() ([]string, string, error) {
+	var files []string
+	for _, file := range a.cfgFiles {
+		if file, err := homedir.Expand(file); err == nil {
+			files = append(files, file)
+		}
+	}
+
+	paths := a.searchPaths()
+	if len(files) == 0 {
+		if file := findConfigFile(paths, a.basename); file != "" {
+			files = append(files, file)
+		} else if !a.configOptional {
+			return nil, "", fmt.Errorf("failed to read configuration file: no %s.{%s} found in %s",
+				a.basename, strings.Join(viper.SupportedExts, ","), strings.Join(paths, ", "))
+		}
+	}
+	if len(files) > 0 {
+		paths = []string{filepath.Dir(files[0])}
+	}
+
+	for _, path := range paths {
+		dir := filepath.Join(path, a.basename+".d")
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() && isSupportedConfig(entry.Name()) {
+				files = append(files, filepath.Join(dir, entry.Name()))
 			}
+		}
 
-			viper.SetConfigName(basename)
+		return files, dir, nil
+	}
+
+	return files, "", nil
+}
+
+// findConfigFile returns the first file named after basename with a supported
+// extension in paths.
+func findConfigFile(paths []string, basename string) string {
+	for _, path := range paths {
+		for _, ext := range viper.SupportedExts {
+			file := filepath.Join(path, basename+"."+ext)
+			if info, err := os.Stat(file); err == nil && !info.IsDir() {
+				return file
+			}
+		}
+	}
+
+	return ""
+}
+
+func isSupportedConfig(name string) bool {
+	ext := strings.TrimPrefix(filepath.Ext(name), ".")
+	for _, supported := range viper.SupportedExts {
+		if ext == supported {
+			return true
 		}
+	}
+
+	return false
+}
+
+// loadConfig reads in the configuration files. It is run before the command is
+// executed.
+func (a *App) loadConfig() error {
+	cfg, err := a.mergeConfig()
+	if err != nil {
+		return err
+	}
+	if err := a.setConfig(cfg.content); err != nil {
+		return err
+	}
+	a.storeConfig(cfg)
+
+	return nil
+}
+
+// mergedConfig is the result of merging the configuration files.
+type mergedConfig struct {
+	files     []string
+	dropInDir string
+	// origins maps every key to the file its value was last read from.
+	origins map[string]string
+	// content is the merged configuration, encoded as YAML.
+	content []byte
+	// emptyFiles are the files without any key.
+	emptyFiles []string
+}
 
-		if err := viper.ReadInConfig(); err != nil {
-			_, _ = fmt.Fprintf(os.Stderr, "Error: failed to read configuration file(%s): %v\n", cfgFile, err)
-			os.Exit(1)
+// mergeConfig merges the configuration files in order.
+func (a *App) mergeConfig() (*mergedConfig, error) {
+	files, dropInDir, err := a.configFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &mergedConfig{files: files, dropInDir: dropInDir, origins: map[string]string{}}
+	merged := viper.New()
+	for _, file := range files {
+		layer := viper.New()
+		layer.SetConfigFile(file)
+		if err := layer.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("failed to read configuration file(%s): %w", file, err)
+		}
+		keys := layer.AllKeys()
+		if len(keys) == 0 {
+			cfg.emptyFiles = append(cfg.emptyFiles, file)
+		}
+		for _, key := range keys {
+			cfg.origins[key] = file
+		}
+		if err := merged.MergeConfigMap(layer.AllSettings()); err != nil {
+			return nil, fmt.Errorf("failed to merge configuration file(%s): %w", file, err)
 		}
-	})
-}
-
-func printConfig(allKeys []string) {
-	if keys := allKeys; len(keys) > 0 {
-		fmt.Printf("%v Configuration items:\n", progressMessage)
-		table := uitable.New()
-		table.Separator = " "
-		table.MaxColWidth = 80
-		table.RightAlign(0)
-		for _, k := range keys {
-			table.AddRow(fmt.Sprintf("%s:", k), viper.Get(k))
+	}
+
+	cfg.content, err = yaml.Marshal(merged.AllSettings())
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// setConfig replaces the configuration layer of the viper instance with the
+// given YAML content.
+func (a *App) setConfig(content []byte) error {
+	v := a.Viper()
+	v.SetConfigType("yaml")
+
+	return v.ReadConfig(bytes.NewReader(content))
+}
+
+// storeConfig records the configuration files in use and where the keys come
+// from.
+func (a *App) storeConfig(cfg *mergedConfig) {
+	a.resolvedMu.Lock()
+	a.configFilesUsed = cfg.files
+	a.configOrigins = cfg.origins
+	a.configDropInDir = cfg.dropInDir
+	a.configContent = cfg.content
+	a.resolvedMu.Unlock()
+}
+
+// configOrigin returns the configuration file the value of key was last read
+// from. For a key holding a map, it is the file of the last of its nested keys
+// in merge order.
+func (a *App) configOrigin(key string) string {
+	a.resolvedMu.RLock()
+	defer a.resolvedMu.RUnlock()
+
+	if origin, ok := a.configOrigins[key]; ok {
+		return origin
+	}
+	origin := ""
+	for _, file := range a.configFilesUsed {
+		for k, v := range a.configOrigins {
+			if v == file && strings.HasPrefix(k, key+".") {
+				origin = file
+			}
 		}
-		fmt.Printf("%v\n", table)
 	}
+
+	return origin
 }
 
 /*
@@ -0,0 +1,448 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	goflag "flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gosuri/uitable"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/yuanbaopig/app/featuregate"
+	"github.com/yuanbaopig/app/fname"
+	"gopkg.in/yaml.v3"
+)
+
+// builtinKeys are the keys of the flags added by the application itself,
+// which are not part of the configuration.
+var builtinKeys = map[string]bool{
+	configFlagName:       true,
+	flagHelp:             true,
+	helpFormatFlagName:   true,
+	colorFlagName:        true,
+	"version":            true,
+	featuregate.FlagName: true,
+}
+
+// isBuiltinKey reports whether the configuration key belongs to a flag added
+// by the application itself, or to a flag of the go flag.CommandLine added by
+// fname.InitFlags, whose "_" separators are normalized to "-".
+func isBuiltinKey(key string) bool {
+	if builtinKeys[key] {
+		return true
+	}
+
+	return goflag.CommandLine.Lookup(key) != nil || goflag.CommandLine.Lookup(strings.ReplaceAll(key, "-", "_")) != nil
+}
+
+// WithConfigCommands adds the config command with the view, validate, init
+// and diff subcommands to the application.
+func WithConfigCommands() Option {
+	return func(a *App) {
+		a.configCommands = true
+	}
+}
+
+func (a *App) configCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect, validate and create configuration files.",
+	}
+	cmd.AddCommand(
+		a.configViewCommand(),
+		a.configValidateCommand(),
+		a.configInitCommand(),
+		a.configDiffCommand(),
+	)
+
+	return cmd
+}
+
+func (a *App) configViewCommand() *cobra.Command {
+	var output string
+	cmd := &cobra.Command{
+		Use:   "view",
+		Short: "Print the effective configuration merged from defaults, config file, environment and flags.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := a.bindOptions(cmd.InheritedFlags(), a.options); err != nil {
+				return err
+			}
+
+			return encodeConfig(cmd.OutOrStdout(), output, a.effectiveConfig())
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "yaml", "Output format. One of: yaml|json|toml.")
+
+	return cmd
+}
+
+func (a *App) configValidateCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate",
+		Short: "Validate the configuration without running the application.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := a.bindOptions(cmd.InheritedFlags(), a.options); err != nil {
+				return err
+			}
+			if err := a.checkConstraints(a.namedFlagSets, cmd.InheritedFlags()); err != nil {
+				return err
+			}
+			if a.options != nil {
+				if err := a.completeOptions(a.options); err != nil {
+					return err
+				}
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Configuration `%s` is valid.\n", strings.Join(a.ConfigFilesUsed(), ", "))
+
+			return nil
+		},
+	}
+}
+
+func (a *App) configInitCommand() *cobra.Command {
+	var force bool
+	cmd := &cobra.Command{
+		Use:   "init [FILE]",
+		Short: "Write a starter configuration file, or print it if no file is given.",
+		Args:  cobra.MaximumNArgs(1),
+		// the configuration file does not need to exist yet
+		PersistentPreRunE: func(*cobra.Command, []string) error {
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var buf bytes.Buffer
+			writeStarterConfig(&buf, a.namedFlagSets)
+			if len(args) == 0 {
+				_, err := cmd.OutOrStdout().Write(buf.Bytes())
+
+				return err
+			}
+
+			if _, err := os.Stat(args[0]); err == nil && !force {
+				return fmt.Errorf("configuration file %s already exists, use --force to overwrite it", args[0])
+			}
+			if err := os.WriteFile(args[0], buf.Bytes(), 0o644); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Configuration file `%s` written.\n", args[0])
+
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&force, "force", false, "Overwrite the configuration file if it already exists.")
+
+	return cmd
+}
+
+func (a *App) configDiffCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "diff",
+		Short: "Show the configuration keys whose effective value differs from the default.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := a.bindOptions(cmd.InheritedFlags(), a.options); err != nil {
+				return err
+			}
+
+			table := uitable.New()
+			table.Separator = " "
+			table.MaxColWidth = 80
+			table.AddRow("KEY", "DEFAULT", "VALUE", "SOURCE", "ORIGIN")
+			for _, item := range a.sortedProvenance() {
+				if isBuiltinKey(item.Key) || item.Source == SourceDefault {
+					continue
+				}
+				def := "<unset>"
+				flag := cmd.Flags().Lookup(item.Key)
+				if flag != nil {
+					if isDefaultValue(flag, item.Value) {
+						continue
+					}
+					def = flag.DefValue
+				}
+				value := fmt.Sprint(item.Value)
+				if item.Sensitive {
+					def, value = fname.RedactedValue, fname.RedactedValue
+				}
+				table.AddRow(item.Key, def, value, item.Source, item.Origin)
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), table)
+
+			return nil
+		},
+	}
+}
+
+// isDefaultValue reports whether the configuration value equals the default
+// value of the flag. Both are compared as the type of the flag, since the
+// default is formatted by pflag, e.g. "[a,b]", and the value is decoded from
+// the configuration file, e.g. []interface{}{"a", "b"}.
+func isDefaultValue(flag *pflag.Flag, value interface{}) bool {
+	switch typ := flag.Value.Type(); {
+	case typ == "duration":
+		return normalizeDuration(flag.DefValue) == normalizeDuration(value)
+	case strings.HasSuffix(typ, "Slice"), strings.HasSuffix(typ, "Array"):
+		def, values := listItems(flag.DefValue), listItems(value)
+		if typ == "durationSlice" {
+			for i := range def {
+				def[i] = normalizeDuration(def[i])
+			}
+			for i := range values {
+				values[i] = normalizeDuration(values[i])
+			}
+		}
+
+		return reflect.DeepEqual(def, values)
+	case strings.HasPrefix(typ, "stringTo"):
+		return reflect.DeepEqual(mapItems(flag.DefValue), mapItems(value))
+	default:
+		return normalizeValue(flag, flag.DefValue) == normalizeValue(flag, fmt.Sprint(value))
+	}
+}
+
+func normalizeDuration(v interface{}) string {
+	if d, ok := v.(time.Duration); ok {
+		return d.String()
+	}
+	d, err := time.ParseDuration(fmt.Sprint(v))
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+
+	return d.String()
+}
+
+// listItems returns the items of a list value, which is either a slice or a
+// comma-separated string optionally enclosed in brackets, as pflag formats it.
+func listItems(v interface{}) []string {
+	items := []string{}
+	if s, ok := v.(string); ok {
+		if s = strings.Trim(s, "[]"); s != "" {
+			for _, item := range strings.Split(s, ",") {
+				items = append(items, strings.TrimSpace(item))
+			}
+		}
+
+		return items
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return append(items, fmt.Sprint(v))
+	}
+	for i := 0; i < rv.Len(); i++ {
+		items = append(items, fmt.Sprint(rv.Index(i).Interface()))
+	}
+
+	return items
+}
+
+// mapItems returns the entries of a map value, which is either a map or a
+// string of comma-separated key=value pairs optionally enclosed in brackets.
+func mapItems(v interface{}) map[string]string {
+	items := map[string]string{}
+	if s, ok := v.(string); ok {
+		for _, item := range listItems(s) {
+			key, value, _ := strings.Cut(item, "=")
+			items[key] = value
+		}
+
+		return items
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Map {
+		return items
+	}
+	iter := rv.MapRange()
+	for iter.Next() {
+		items[fmt.Sprint(iter.Key().Interface())] = fmt.Sprint(iter.Value().Interface())
+	}
+
+	return items
+}
+
+// normalizeValue parses s with a fresh value of the type of the flag value and
+// returns its string form, e.g. "100Mi" for the byte size "104857600", or s
+// if it cannot be parsed.
+func normalizeValue(flag *pflag.Flag, s string) (normalized string) {
+	t := reflect.TypeOf(flag.Value)
+	if t.Kind() != reflect.Ptr {
+		return s
+	}
+	// 部分 pflag 内置类型的零值不可用，Set 会 panic，此时按原值比较
+	defer func() {
+		if recover() != nil {
+			normalized = s
+		}
+	}()
+	value, ok := reflect.New(t.Elem()).Interface().(pflag.Value)
+	if !ok || value.Set(s) != nil {
+		return s
+	}
+
+	return value.String()
+}
+
+// effectiveConfig returns the merged configuration as a nested map, without
+// the keys of the builtin flags and with sensitive values redacted.
+func (a *App) effectiveConfig() map[string]interface{} {
+	settings := map[string]interface{}{}
+	for _, item := range a.sortedProvenance() {
+		if isBuiltinKey(item.Key) {
+			continue
+		}
+		value := item.Value
+		if item.Sensitive {
+			value = fname.RedactedValue
+		}
+
+		m := settings
+		path := strings.Split(item.Key, ".")
+		for _, p := range path[:len(path)-1] {
+			child, ok := m[p].(map[string]interface{})
+			if !ok {
+				child = map[string]interface{}{}
+				m[p] = child
+			}
+			m = child
+		}
+		m[path[len(path)-1]] = value
+	}
+
+	return settings
+}
+
+func encodeConfig(w io.Writer, format string, settings map[string]interface{}) error {
+	switch format {
+	case "yaml", "yml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(settings); err != nil {
+			return err
+		}
+
+		return enc.Close()
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(settings)
+	case "toml":
+		return toml.NewEncoder(w).Encode(settings)
+	default:
+		return fmt.Errorf("unsupported output format %q, must be one of: yaml|json|toml", format)
+	}
+}
+
+// configNode is a node in the key tree of a starter configuration file.
+type configNode struct {
+	name     string
+	flag     *pflag.Flag
+	children []*configNode
+}
+
+func (n *configNode) child(name string) *configNode {
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+	c := &configNode{name: name}
+	n.children = append(n.children, c)
+
+	return c
+}
+
+// writeStarterConfig writes a YAML configuration file containing every flag of
+// fss with its default value, commented with the usage of the flag. Keys are
+// nested by the dots in the flag names, in the order of the flag sets.
+func writeStarterConfig(w io.Writer, fss fname.NamedFlagSets) {
+	root := &configNode{}
+	for _, name := range fss.Order {
+		fss.FlagSets[name].VisitAll(func(flag *pflag.Flag) {
+			if isBuiltinKey(flag.Name) {
+				return
+			}
+			n := root
+			for _, p := range strings.Split(flag.Name, ".") {
+				n = n.child(p)
+			}
+			n.flag = flag
+		})
+	}
+
+	for _, c := range root.children {
+		writeConfigNode(w, c, 0)
+	}
+}
+
+func writeConfigNode(w io.Writer, n *configNode, depth int) {
+	indent := strings.Repeat("  ", depth)
+	if n.flag == nil || len(n.children) > 0 {
+		fmt.Fprintf(w, "%s%s:\n", indent, n.name)
+		sort.SliceStable(n.children, func(i, j int) bool { return n.children[i].flag != nil && n.children[j].flag == nil })
+		for _, c := range n.children {
+			writeConfigNode(w, c, depth+1)
+		}
+
+		return
+	}
+
+	if n.flag.Usage != "" {
+		for _, line := range strings.Split(n.flag.Usage, "\n") {
+			fmt.Fprintf(w, "%s# %s\n", indent, line)
+		}
+	}
+	fmt.Fprintf(w, "%s%s: %s\n", indent, n.name, starterValue(n.flag))
+}
+
+// starterValue returns the default value of the flag formatted as a YAML
+// value.
+func starterValue(flag *pflag.Flag) string {
+	if fname.IsSensitive(flag) {
+		return `""`
+	}
+
+	switch typ := flag.Value.Type(); {
+	case typ == "bool", typ == "duration", strings.HasPrefix(typ, "int"),
+		strings.HasPrefix(typ, "uint"), strings.HasPrefix(typ, "float"):
+		return flag.DefValue
+	case strings.HasSuffix(typ, "Slice"), strings.HasSuffix(typ, "Array"):
+		items := strings.Trim(flag.DefValue, "[]")
+		if items == "" {
+			return "[]"
+		}
+		var quoted []string
+		for _, item := range strings.Split(items, ",") {
+			quoted = append(quoted, yamlString(item))
+		}
+
+		return "[" + strings.Join(quoted, ", ") + "]"
+	default:
+		return yamlString(flag.DefValue)
+	}
+}
+
+func yamlString(s string) string {
+	out, err := yaml.Marshal(s)
+	if err != nil {
+		return fmt.Sprintf("%q", s)
+	}
+
+	return strings.TrimSuffix(string(out), "\n")
+}
@@ -0,0 +1,54 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yuanbaopig/app/flagvalue"
+)
+
+type diffOptions struct {
+	Name    string             `flag:"name" default:"default" usage:"Name."`
+	Tags    []string           `flag:"tags" default:"a,b" usage:"Tags."`
+	Ports   []int              `flag:"ports" default:"80,443" usage:"Ports."`
+	Labels  map[string]string  `flag:"labels" default:"env=dev" usage:"Labels."`
+	Timeout time.Duration      `flag:"timeout" default:"1m" usage:"Timeout."`
+	Size    flagvalue.ByteSize `flag:"size" default:"100Mi" usage:"Size."`
+}
+
+func (o *diffOptions) Validate() []error { return nil }
+
+func TestConfigDiffComparesTypedValues(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "test.yaml")
+	// 除 name 与 ports 外，其余配置项都与默认值相同，只是格式不同
+	writeConfig(t, file, `name: changed
+tags: [a, b]
+ports: [80, 8443]
+labels:
+  env: dev
+timeout: 60s
+size: 104857600
+`)
+	out := &bytes.Buffer{}
+	a := NewApp("test", "test", WithOptions(&diffOptions{}), WithConfigCommands(), WithIO(nil, out, io.Discard))
+	if code, err := a.Execute(context.Background(), []string{"config", "diff", "-c", file}); code != 0 || err != nil {
+		t.Fatalf("Execute() = %d, %v, want 0, nil", code, err)
+	}
+
+	var keys []string
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n")[1:] {
+		keys = append(keys, strings.Fields(line)[0])
+	}
+	if got, want := strings.Join(keys, ","), "name,ports"; got != want {
+		t.Errorf("config diff keys = %s, want %s\n%s", got, want, out.String())
+	}
+}
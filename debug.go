@@ -0,0 +1,266 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/marmotedu/errors"
+	"github.com/spf13/pflag"
+	"github.com/yuanbaopig/app/fname"
+	"github.com/yuanbaopig/app/version"
+)
+
+const debugSection = "debug"
+
+// debugServerOptions are the options of the debug server.
+type debugServerOptions struct {
+	Addr        string `flag:"addr" usage:"Address the debug server listens on. The server is disabled if empty."`
+	EnablePprof bool   `flag:"enable-pprof" usage:"Serve the runtime profiling data under /debug/pprof."`
+}
+
+// debugOptions nests the debug server options under the "debug" flag prefix
+// and configuration key, e.g. --debug.addr and debug.addr.
+type debugOptions struct {
+	Debug *debugServerOptions `flag:"debug" section:"debug"`
+}
+
+// Validate validates the debug server options.
+func (o *debugOptions) Validate() []error {
+	if o.Debug.Addr == "" {
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(o.Debug.Addr); err != nil {
+		return []error{fmt.Errorf("invalid --debug.addr %q: %w", o.Debug.Addr, err)}
+	}
+
+	return nil
+}
+
+// WithDebugServer adds the debug server options to the application, as the
+// "debug" flag section. While the run function is running, an HTTP server
+// listening on --debug.addr serves:
+//
+//	/debug/pprof  the runtime profiling data, if --debug.enable-pprof is set
+//	/debug/vars   the variables exported by expvar
+//	/version      the version information
+//	/flags        the flags, with the sensitive values redacted
+//	/config       the effective configuration, with the sensitive values redacted
+//	/healthz      the liveness, which is always ok
+//	/readyz       the readiness, set by the run function with SetReady
+//
+// The sensitive flag values are redacted from the command line served by
+// /debug/vars and /debug/pprof/cmdline. The server is shut down when the run
+// function returns.
+func WithDebugServer() Option {
+	return func(a *App) {
+		a.debug = &debugServerOptions{Addr: "127.0.0.1:6060"}
+	}
+}
+
+type readinessKey struct{}
+
+// SetReady sets the readiness reported by the /readyz endpoint of the debug
+// server. The run function calls it with its context once it is ready to
+// serve, and again when it stops being ready. The application is not ready
+// until SetReady is called. SetReady does nothing without debug server.
+func SetReady(ctx context.Context, ready bool) {
+	if r, ok := ctx.Value(readinessKey{}).(*atomic.Bool); ok {
+		r.Store(ready)
+	}
+}
+
+// addDebugFlags adds the flags of the debug server options to the debug
+// section of fss and to fs.
+func (a *App) addDebugFlags(fss *fname.NamedFlagSets, fs *pflag.FlagSet) {
+	for _, f := range fname.FromStruct(&debugOptions{Debug: a.debug}).FlagSets {
+		fss.FlagSet(debugSection).AddFlagSet(f)
+		fs.AddFlagSet(f)
+	}
+}
+
+// bindDebugOptions unmarshals the debug server options from the
+// configuration, unless disabled, and validates them.
+func (a *App) bindDebugOptions() error {
+	if a.debug == nil {
+		return nil
+	}
+	opts := &debugOptions{Debug: a.debug}
+	if !a.noConfig {
+		if err := a.Viper().Unmarshal(opts, decoderConfig(opts)); err != nil {
+			return err
+		}
+	}
+
+	return errors.NewAggregate(opts.Validate())
+}
+
+// startDebugServer starts the debug server, unless its address is empty. It
+// returns the context carrying the readiness of the server and the function
+// which shuts the server down.
+func (a *App) startDebugServer(ctx context.Context, fs *pflag.FlagSet) (context.Context, func(), error) {
+	if a.debug.Addr == "" {
+		return ctx, func() {}, nil
+	}
+
+	listener, err := net.Listen("tcp", a.debug.Addr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to start debug server: %w", err)
+	}
+	ready := &atomic.Bool{}
+	server := &http.Server{
+		Handler:           a.debugHandler(fs, a.debug.EnablePprof, ready),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintf(a.errOut, "%v debug server failed: %v\n", a.paint(a.errOut, a.theme.Error, "Error:"), err)
+		}
+	}()
+	addr := listener.Addr().String()
+	a.report(EventDebugServer, addr, "Debug server listening on http://%s", addr)
+
+	stop := func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), a.stopTimeout())
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			// 超时后强制关闭仍未结束的连接，例如正在采集的 profile
+			_ = server.Close()
+		}
+		<-done
+	}
+
+	return context.WithValue(ctx, readinessKey{}, ready), stop, nil
+}
+
+// debugHandler returns the handler of the debug server.
+func (a *App) debugHandler(fs *pflag.FlagSet, enablePprof bool, ready *atomic.Bool) http.Handler {
+	mux := http.NewServeMux()
+	if enablePprof {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		// 命令行参数中可能包含敏感的选项值，不能使用 pprof.Cmdline
+		mux.HandleFunc("/debug/pprof/cmdline", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Content-Type-Options", "nosniff")
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			fmt.Fprint(w, strings.Join(redactArgs(fs, os.Args), "\x00"))
+		})
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+	mux.HandleFunc("/debug/vars", func(w http.ResponseWriter, r *http.Request) {
+		writeVars(w, fs)
+	})
+	mux.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintln(w, version.Get().ToJSON())
+	})
+	mux.HandleFunc("/flags", func(w http.ResponseWriter, r *http.Request) {
+		var flags []FlagItem
+		fs.VisitAll(func(flag *pflag.Flag) {
+			flags = append(flags, FlagItem{Name: flag.Name, Value: fname.FlagValue(flag)})
+		})
+		writeJSON(w, flags)
+	})
+	mux.HandleFunc("/config", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, a.effectiveConfig())
+	})
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if !ready.Load() {
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+
+	return mux
+}
+
+// writeVars writes the variables exported by expvar like expvar.Handler, but
+// with the values of the sensitive flags in fs redacted from the cmdline
+// variable.
+func writeVars(w http.ResponseWriter, fs *pflag.FlagSet) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	fmt.Fprintf(w, "{\n")
+	first := true
+	expvar.Do(func(kv expvar.KeyValue) {
+		if !first {
+			fmt.Fprintf(w, ",\n")
+		}
+		first = false
+		value := kv.Value.String()
+		if kv.Key == "cmdline" {
+			cmdline, _ := json.Marshal(redactArgs(fs, os.Args))
+			value = string(cmdline)
+		}
+		fmt.Fprintf(w, "%q: %s", kv.Key, value)
+	})
+	fmt.Fprintf(w, "\n}\n")
+}
+
+// redactArgs returns a copy of the command line args, including the program
+// name, with the values of the sensitive flags in fs replaced with
+// fname.RedactedValue.
+func redactArgs(fs *pflag.FlagSet, args []string) []string {
+	redacted := append([]string(nil), args...)
+	for i := 1; i < len(redacted); i++ {
+		arg := redacted[i]
+		if arg == "--" {
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			continue
+		}
+
+		var flag *pflag.Flag
+		var prefix string
+		hasValue := false
+		if strings.HasPrefix(arg, "--") {
+			name, _, ok := strings.Cut(arg[2:], "=")
+			flag, prefix, hasValue = fs.Lookup(name), "--"+name+"=", ok
+		} else {
+			// 短选项的值可以紧跟在选项后面，例如 -pSECRET 或 -p=SECRET
+			flag, prefix, hasValue = fs.ShorthandLookup(arg[1:2]), arg[:2], len(arg) > 2
+			if strings.HasPrefix(arg[2:], "=") {
+				prefix += "="
+			}
+		}
+		if flag == nil || !fname.IsSensitive(flag) {
+			continue
+		}
+		switch {
+		case hasValue:
+			redacted[i] = prefix + fname.RedactedValue
+		case flag.NoOptDefVal == "" && i+1 < len(redacted):
+			i++
+			redacted[i] = fname.RedactedValue
+		}
+	}
+
+	return redacted
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
@@ -0,0 +1,59 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"io"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+type debugTestOptions struct {
+	Password string `flag:"password" sensitive:"true" usage:"Password."`
+	Token    string `flag:"token" sensitive:"true" usage:"Token."`
+	User     string `flag:"user" usage:"User."`
+}
+
+func (o *debugTestOptions) Validate() []error { return nil }
+
+func TestDebugHandlerRedactsCmdline(t *testing.T) {
+	args := os.Args
+	t.Cleanup(func() { os.Args = args })
+	os.Args = []string{"test", "--password=secret-password", "--token", "secret-token", "--user=admin"}
+
+	a := NewApp("test", "test", WithNoConfig(), WithOptions(&debugTestOptions{}), WithDebugServer(),
+		WithIO(nil, io.Discard, io.Discard))
+	// 与 cobra 执行命令时一样合并 persistent flags
+	fs := a.Command().Flags()
+	fs.AddFlagSet(a.Command().PersistentFlags())
+	handler := a.debugHandler(fs, true, &atomic.Bool{})
+
+	for _, path := range []string{"/debug/vars", "/debug/pprof/cmdline"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		body := rec.Body.String()
+		if strings.Contains(body, "secret") {
+			t.Errorf("%s exposes a sensitive value: %s", path, body)
+		}
+		if !strings.Contains(body, "admin") {
+			t.Errorf("%s does not contain the command line: %s", path, body)
+		}
+	}
+}
+
+func TestRedactArgs(t *testing.T) {
+	a := NewApp("test", "test", WithNoConfig(), WithOptions(&debugTestOptions{}), WithIO(nil, io.Discard, io.Discard))
+	fs := a.Command().Flags()
+	fs.AddFlagSet(a.Command().PersistentFlags())
+
+	got := strings.Join(redactArgs(fs, []string{"test", "--password", "p", "--token=t", "--user", "u", "--", "--password=x"}), " ")
+	want := "test --password ****** --token=****** --user u -- --password=x"
+	if got != want {
+		t.Errorf("redactArgs() = %q, want %q", got, want)
+	}
+}
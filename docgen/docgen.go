@@ -0,0 +1,161 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+// Package docgen renders the documentation of a command tree, with its flags
+// grouped in sections and a reference of the configuration keys, as man
+// pages, markdown or reStructuredText files.
+package docgen
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Format is the format of the generated documentation.
+type Format string
+
+// Define the supported documentation formats.
+const (
+	Man      Format = "man"
+	Markdown Format = "markdown"
+	RST      Format = "rst"
+)
+
+// Doc is the documentation of an application.
+type Doc struct {
+	// Root is the root command of the application.
+	Root *Command
+	// Config is the reference of the configuration keys, which is rendered
+	// in the page of the root command.
+	Config []ConfigKey
+	// Version is the version of the application, printed in man pages.
+	Version string
+}
+
+// Command is the documentation of a command.
+type Command struct {
+	// Path is the full name of the command, e.g. "app config view".
+	Path     string
+	UseLine  string
+	Short    string
+	Long     string
+	Example  string
+	Aliases  []string
+	Sections []Section
+	Commands []*Command
+	parent   *Command
+}
+
+// Section is a named group of flags.
+type Section struct {
+	Name  string
+	Flags []Flag
+	// Constraints describe the relationships between the flags of the
+	// section.
+	Constraints []string
+}
+
+// Flag is the documentation of a flag.
+type Flag struct {
+	Name      string
+	Shorthand string
+	Type      string
+	Default   string
+	Usage     string
+}
+
+// ConfigKey is the documentation of a configuration key.
+type ConfigKey struct {
+	Key         string
+	Type        string
+	Default     string
+	Flag        string
+	Env         string
+	Description string
+}
+
+// Generate writes one file per command of doc in the given format to dir,
+// which is created if it does not exist.
+func Generate(doc *Doc, format Format, dir string) error {
+	var write func(io.Writer, *Doc, *Command) error
+	var ext string
+	switch format {
+	case Man:
+		write, ext = writeMan, ".1"
+	case Markdown:
+		write, ext = writeMarkdown, ".md"
+	case RST:
+		write, ext = writeRST, ".rst"
+	default:
+		return fmt.Errorf("unsupported documentation format %q, must be one of: man|markdown|rst", format)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	return walk(doc.Root, nil, func(cmd *Command) error {
+		f, err := os.Create(filepath.Join(dir, fileName(cmd, format)+ext))
+		if err != nil {
+			return err
+		}
+		if err := write(f, doc, cmd); err != nil {
+			_ = f.Close()
+
+			return err
+		}
+
+		return f.Close()
+	})
+}
+
+// walk calls fn for cmd and its sub commands, depth first, linking every
+// command to its parent.
+func walk(cmd, parent *Command, fn func(*Command) error) error {
+	cmd.parent = parent
+	if err := fn(cmd); err != nil {
+		return err
+	}
+	for _, c := range cmd.Commands {
+		if err := walk(c, cmd, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fileName returns the name of the documentation file of the command without
+// extension: the path joined by "-" for man pages, by "_" otherwise.
+func fileName(cmd *Command, format Format) string {
+	sep := "_"
+	if format == Man {
+		sep = "-"
+	}
+
+	return strings.Join(strings.Fields(cmd.Path), sep)
+}
+
+// related returns the parent and the sub commands of cmd.
+func related(cmd *Command) []*Command {
+	var commands []*Command
+	if cmd.parent != nil {
+		commands = append(commands, cmd.parent)
+	}
+
+	return append(commands, cmd.Commands...)
+}
+
+// flagNames returns the flag as written on the command line, with its
+// shorthand if any.
+func flagNames(flag Flag) string {
+	if flag.Shorthand != "" {
+		return fmt.Sprintf("-%s, --%s", flag.Shorthand, flag.Name)
+	}
+
+	return "--" + flag.Name
+}
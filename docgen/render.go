@@ -0,0 +1,250 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package docgen
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/yuanbaopig/app/fname"
+)
+
+func writeMarkdown(w io.Writer, doc *Doc, cmd *Command) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "## %s\n\n%s\n\n", cmd.Path, cmd.Short)
+	if cmd.Long != "" {
+		fmt.Fprintf(&buf, "### Synopsis\n\n%s\n\n", cmd.Long)
+	}
+	fmt.Fprintf(&buf, "```\n%s\n```\n\n", cmd.UseLine)
+	if len(cmd.Aliases) > 0 {
+		fmt.Fprintf(&buf, "### Aliases\n\n%s\n\n", strings.Join(cmd.Aliases, ", "))
+	}
+	if cmd.Example != "" {
+		fmt.Fprintf(&buf, "### Examples\n\n```\n%s\n```\n\n", cmd.Example)
+	}
+
+	for _, section := range cmd.Sections {
+		fmt.Fprintf(&buf, "### %s\n\n", fname.SectionTitle(section.Name))
+		buf.WriteString("| Flag | Type | Default | Description |\n| --- | --- | --- | --- |\n")
+		for _, flag := range section.Flags {
+			fmt.Fprintf(&buf, "| `%s` | %s | %s | %s |\n",
+				flagNames(flag), markdownCell(flag.Type), markdownCode(flag.Default), markdownCell(flag.Usage))
+		}
+		buf.WriteString("\n")
+		for _, c := range section.Constraints {
+			fmt.Fprintf(&buf, "* %s\n", c)
+		}
+		if len(section.Constraints) > 0 {
+			buf.WriteString("\n")
+		}
+	}
+
+	if cmd.parent == nil && len(doc.Config) > 0 {
+		buf.WriteString("### Configuration\n\n")
+		buf.WriteString("| Key | Type | Default | Flag | Environment variable | Description |\n")
+		buf.WriteString("| --- | --- | --- | --- | --- | --- |\n")
+		for _, key := range doc.Config {
+			fmt.Fprintf(&buf, "| `%s` | %s | %s | %s | %s | %s |\n", key.Key, markdownCell(key.Type),
+				markdownCode(key.Default), markdownCode(key.Flag), markdownCode(key.Env), markdownCell(key.Description))
+		}
+		buf.WriteString("\n")
+	}
+
+	if commands := related(cmd); len(commands) > 0 {
+		buf.WriteString("### See also\n\n")
+		for _, c := range commands {
+			fmt.Fprintf(&buf, "* [%s](%s.md) - %s\n", c.Path, fileName(c, Markdown), c.Short)
+		}
+	}
+
+	_, err := w.Write(buf.Bytes())
+
+	return err
+}
+
+func markdownCode(s string) string {
+	if s == "" {
+		return ""
+	}
+
+	return "`" + s + "`"
+}
+
+func markdownCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", "<br>").Replace(s)
+}
+
+func writeRST(w io.Writer, doc *Doc, cmd *Command) error {
+	var buf bytes.Buffer
+	rstHeading(&buf, cmd.Path, "=")
+	fmt.Fprintf(&buf, "%s\n\n", cmd.Short)
+	if cmd.Long != "" {
+		rstHeading(&buf, "Synopsis", "-")
+		fmt.Fprintf(&buf, "%s\n\n", cmd.Long)
+	}
+	fmt.Fprintf(&buf, "::\n\n  %s\n\n", cmd.UseLine)
+	if len(cmd.Aliases) > 0 {
+		rstHeading(&buf, "Aliases", "-")
+		fmt.Fprintf(&buf, "%s\n\n", strings.Join(cmd.Aliases, ", "))
+	}
+	if cmd.Example != "" {
+		rstHeading(&buf, "Examples", "-")
+		fmt.Fprintf(&buf, "::\n\n%s\n\n", indent(cmd.Example, "  "))
+	}
+
+	for _, section := range cmd.Sections {
+		rstHeading(&buf, fname.SectionTitle(section.Name), "-")
+		rows := [][]string{{"Flag", "Type", "Default", "Description"}}
+		for _, flag := range section.Flags {
+			rows = append(rows, []string{rstCode(flagNames(flag)), flag.Type, rstCode(flag.Default), flag.Usage})
+		}
+		rstTable(&buf, rows)
+		for _, c := range section.Constraints {
+			fmt.Fprintf(&buf, "* %s\n", c)
+		}
+		if len(section.Constraints) > 0 {
+			buf.WriteString("\n")
+		}
+	}
+
+	if cmd.parent == nil && len(doc.Config) > 0 {
+		rstHeading(&buf, "Configuration", "-")
+		rows := [][]string{{"Key", "Type", "Default", "Flag", "Environment variable", "Description"}}
+		for _, key := range doc.Config {
+			rows = append(rows, []string{rstCode(key.Key), key.Type, rstCode(key.Default),
+				rstCode(key.Flag), rstCode(key.Env), key.Description})
+		}
+		rstTable(&buf, rows)
+	}
+
+	if commands := related(cmd); len(commands) > 0 {
+		rstHeading(&buf, "See also", "-")
+		for _, c := range commands {
+			fmt.Fprintf(&buf, "* `%s <%s.rst>`_ - %s\n", c.Path, fileName(c, RST), c.Short)
+		}
+	}
+
+	_, err := w.Write(buf.Bytes())
+
+	return err
+}
+
+func rstHeading(buf *bytes.Buffer, heading, underline string) {
+	fmt.Fprintf(buf, "%s\n%s\n\n", heading, strings.Repeat(underline, len(heading)))
+}
+
+func rstCode(s string) string {
+	if s == "" {
+		return ""
+	}
+
+	return "``" + s + "``"
+}
+
+// rstTable writes rows as a list table, whose first row is the header.
+func rstTable(buf *bytes.Buffer, rows [][]string) {
+	buf.WriteString(".. list-table::\n   :header-rows: 1\n\n")
+	for _, row := range rows {
+		for i, cell := range row {
+			prefix := "     "
+			if i == 0 {
+				prefix = "   * "
+			}
+			fmt.Fprintf(buf, "%s- %s\n", prefix, strings.ReplaceAll(cell, "\n", " "))
+		}
+	}
+	buf.WriteString("\n")
+}
+
+func indent(s, prefix string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = prefix + line
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func writeMan(w io.Writer, doc *Doc, cmd *Command) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, ".TH \"%s\" \"1\" \"\" \"%s\" \"\"\n", strings.ToUpper(fileName(cmd, Man)), manEscape(doc.Version))
+	fmt.Fprintf(&buf, ".SH NAME\n%s \\- %s\n", manEscape(fileName(cmd, Man)), manEscape(cmd.Short))
+	fmt.Fprintf(&buf, ".SH SYNOPSIS\n.B %s\n", manEscape(cmd.UseLine))
+	if cmd.Long != "" {
+		fmt.Fprintf(&buf, ".SH DESCRIPTION\n%s\n", manText(cmd.Long))
+	}
+	if len(cmd.Sections) > 0 {
+		buf.WriteString(".SH OPTIONS\n")
+	}
+	for _, section := range cmd.Sections {
+		fmt.Fprintf(&buf, ".SS %s\n", manEscape(fname.SectionTitle(section.Name)))
+		for _, flag := range section.Flags {
+			fmt.Fprintf(&buf, ".TP\n.B %s\n", manEscape(flagNames(flag)+" "+flag.Type))
+			usage := flag.Usage
+			if flag.Default != "" {
+				usage += fmt.Sprintf(" (default %s)", flag.Default)
+			}
+			fmt.Fprintf(&buf, "%s\n", manText(usage))
+		}
+		for _, c := range section.Constraints {
+			fmt.Fprintf(&buf, ".IP \\(bu 2\n%s\n", manEscape(c))
+		}
+	}
+	if cmd.Example != "" {
+		fmt.Fprintf(&buf, ".SH EXAMPLES\n.nf\n%s\n.fi\n", manEscape(cmd.Example))
+	}
+
+	if cmd.parent == nil && len(doc.Config) > 0 {
+		buf.WriteString(".SH CONFIGURATION\n")
+		for _, key := range doc.Config {
+			fmt.Fprintf(&buf, ".TP\n.B %s\n", manEscape(key.Key))
+			var refs []string
+			refs = append(refs, "type "+key.Type)
+			if key.Default != "" {
+				refs = append(refs, "default "+key.Default)
+			}
+			if key.Flag != "" {
+				refs = append(refs, "flag "+key.Flag)
+			}
+			if key.Env != "" {
+				refs = append(refs, "environment variable "+key.Env)
+			}
+			fmt.Fprintf(&buf, "%s\n.br\n%s\n", manText(key.Description), manEscape(strings.Join(refs, ", ")))
+		}
+	}
+
+	if commands := related(cmd); len(commands) > 0 {
+		buf.WriteString(".SH SEE ALSO\n")
+		names := make([]string, 0, len(commands))
+		for _, c := range commands {
+			names = append(names, fmt.Sprintf("\\fB%s\\fP(1)", manEscape(fileName(c, Man))))
+		}
+		fmt.Fprintf(&buf, "%s\n", strings.Join(names, ", "))
+	}
+
+	_, err := w.Write(buf.Bytes())
+
+	return err
+}
+
+// manEscape escapes the characters of s which are special in roff.
+func manEscape(s string) string {
+	return strings.NewReplacer(`\`, `\e`, "-", `\-`).Replace(s)
+}
+
+// manText escapes s and starts every line which begins with a control
+// character with a zero-width space.
+func manText(s string) string {
+	lines := strings.Split(manEscape(s), "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			lines[i] = `\&` + line
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
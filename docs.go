@@ -0,0 +1,122 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/yuanbaopig/app/docgen"
+	"github.com/yuanbaopig/app/flagvalue"
+)
+
+// GenerateDocs writes the documentation of the application, one file per
+// command in the given format, to dir. The page of the root command also
+// lists the configuration keys with their flag and environment variable.
+func (a *App) GenerateDocs(format docgen.Format, dir string) error {
+	return docgen.Generate(a.docs(), format, dir)
+}
+
+// docsCommand returns the hidden gen-docs command, which generates the
+// documentation of the application.
+func (a *App) docsCommand() *cobra.Command {
+	format := flagvalue.NewEnum(string(docgen.Markdown), string(docgen.Man), string(docgen.Markdown), string(docgen.RST))
+	var dir string
+
+	cmd := &cobra.Command{
+		Use:    "gen-docs",
+		Short:  "Generate the documentation of the application.",
+		Args:   cobra.NoArgs,
+		Hidden: true,
+		// 生成文档不需要读取配置文件
+		PersistentPreRunE: func(*cobra.Command, []string) error {
+			return nil
+		},
+		RunE: func(*cobra.Command, []string) error {
+			return a.GenerateDocs(docgen.Format(format.Value), dir)
+		},
+	}
+	cmd.Flags().Var(&format, "format", "Format of the documentation.")
+	cmd.Flags().StringVar(&dir, "out", "docs", "Directory the documentation is written to.")
+
+	return cmd
+}
+
+// docs returns the documentation of the application, built from its
+// specification.
+func (a *App) docs() *docgen.Doc {
+	spec := a.Spec()
+
+	return &docgen.Doc{
+		Root:    commandDoc(spec.Command),
+		Config:  configKeys(spec.Command),
+		Version: spec.Version.GitVersion,
+	}
+}
+
+// commandDoc returns the documentation of the command and its sub commands.
+func commandDoc(spec CommandSpec) *docgen.Command {
+	doc := &docgen.Command{
+		Path:    spec.Path,
+		UseLine: spec.Use,
+		Short:   spec.Short,
+		Long:    spec.Long,
+		Example: spec.Example,
+		Aliases: spec.Aliases,
+	}
+	for _, s := range spec.Sections {
+		section := docgen.Section{Name: s.Name, Constraints: s.Constraints}
+		for _, flag := range s.Flags {
+			section.Flags = append(section.Flags, docgen.Flag{
+				Name:      flag.Name,
+				Shorthand: flag.Shorthand,
+				Type:      flag.Type,
+				Default:   flag.Default,
+				Usage:     flag.Usage,
+			})
+		}
+		doc.Sections = append(doc.Sections, section)
+	}
+	for _, sub := range spec.Commands {
+		doc.Commands = append(doc.Commands, commandDoc(sub))
+	}
+
+	return doc
+}
+
+// configKeys returns the documentation of the configuration keys of the
+// command and its sub commands, sorted by key.
+func configKeys(spec CommandSpec) []docgen.ConfigKey {
+	seen := map[string]bool{}
+	var keys []docgen.ConfigKey
+	var walk func(CommandSpec)
+	walk = func(spec CommandSpec) {
+		for _, section := range spec.Sections {
+			for _, flag := range section.Flags {
+				if flag.Key == "" || seen[flag.Key] {
+					continue
+				}
+				seen[flag.Key] = true
+				keys = append(keys, docgen.ConfigKey{
+					Key:         flag.Key,
+					Type:        flag.Type,
+					Default:     flag.Default,
+					Flag:        "--" + flag.Name,
+					Env:         flag.Env,
+					Description: flag.Usage,
+				})
+			}
+		}
+		for _, sub := range spec.Commands {
+			walk(sub)
+		}
+	}
+	walk(spec)
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Key < keys[j].Key
+	})
+
+	return keys
+}
@@ -1,10 +1,9 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
-	"github.com/spf13/viper"
 	"github.com/yuanbaopig/app"
 	"github.com/yuanbaopig/app/fname"
 )
@@ -34,11 +33,11 @@ func (o *options) Complete() error {
 }
 
 type MySQLOptions struct {
-	host *string
+	Host string
 }
 
 func (m *MySQLOptions) AddFlags(fs *pflag.FlagSet) {
-	fs.String("mysql.host", "127.0.0.1", ""+
+	fs.StringVar(&m.Host, "mysql.host", "127.0.0.1", ""+
 		"MySQL service host address. If left blank, the following related mysql options will be ignored.")
 
 }
@@ -50,12 +49,12 @@ func (m *MySQLOptions) Validate() []error {
 }
 
 type RedisOption struct {
-	host *string
+	Host string
 }
 
 func (r *RedisOption) AddFlags(fs *pflag.FlagSet) {
 
-	fs.String("redis.host", "127.0.0.1", ""+
+	fs.StringVar(&r.Host, "redis.host", "127.0.0.1", ""+
 		"redis service host address. If left blank, the following related mysql options will be ignored.")
 
 }
@@ -86,15 +85,16 @@ func main() {
 		app.WithNoVersion(),
 		app.WithNoConfig(),
 		app.WithOptions(o),
-		app.WithRunFunc(run),
+		app.WithRunContextFunc(run),
 	).Run()
 
 }
 
-func run(cmd *cobra.Command, args []string) error {
+func run(ctx context.Context, args []string, opts app.CliOptions) error {
+	o := opts.(*options)
 	fmt.Println("test")
-	fmt.Println(viper.GetString("mysql.host"))
-	fmt.Println(viper.GetString("redis.host"))
+	fmt.Println(o.MySQLOptions.Host)
+	fmt.Println(o.RedisOption.Host)
 	fmt.Println(args)
 	return nil
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -17,7 +18,7 @@ func main() {
 		app.WithNoConfig(),
 		app.WithDescription("description"),
 		app.WithNoVersion(),
-		app.WithRunFunc(func(cmd *cobra.Command, args []string) error {
+		app.WithRunContextFunc(func(ctx context.Context, args []string, opts app.CliOptions) error {
 			fmt.Println(port)
 			fmt.Println(args)
 			return nil
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/yuanbaopig/app"
+)
+
+type options struct {
+	MySQL *MySQLOptions `flag:"mysql"`
+	Redis *RedisOptions `flag:"redis"`
+}
+
+func (o *options) Validate() []error {
+	return nil
+}
+
+type MySQLOptions struct {
+	Host     string        `flag:"host" short:"H" default:"127.0.0.1" usage:"MySQL service host address."`
+	Port     int           `flag:"port" default:"3306" validate:"min=1,max=65535" usage:"MySQL service port."`
+	Password string        `flag:"password" sensitive:"true" usage:"Password for access to mysql."`
+	Timeout  time.Duration `flag:"timeout" default:"5s" usage:"Timeout of connecting to mysql."`
+}
+
+type RedisOptions struct {
+	Addrs  []string          `flag:"addrs" default:"127.0.0.1:6379" usage:"Addresses of the redis cluster."`
+	Labels map[string]string `flag:"labels" usage:"Labels of the redis client, e.g. env=prod,zone=a."`
+}
+
+func main() {
+	o := &options{}
+
+	app.NewApp("test", "test",
+		app.WithNoVersion(),
+		app.WithConfigOptional(),
+		app.WithOptions(o),
+		app.WithRunContextFunc(run),
+	).Run()
+}
+
+func run(ctx context.Context, args []string, opts app.CliOptions) error {
+	o := opts.(*options)
+	fmt.Println(o.MySQL.Host, o.MySQL.Port, o.MySQL.Timeout)
+	fmt.Println(o.Redis.Addrs, o.Redis.Labels)
+	return nil
+}
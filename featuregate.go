@@ -0,0 +1,75 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/yuanbaopig/app/featuregate"
+)
+
+// WithFeatureGate adds the feature-gates flag, which sets the features of
+// gate, to the global flags. The features can also be set by the feature-gates
+// key of the configuration file, as a map of names to states, or by the
+// environment variable. The gate is passed to the run functions in their
+// context, where featuregate.Enabled queries it.
+func WithFeatureGate(gate *featuregate.FeatureGate) Option {
+	return func(a *App) {
+		a.featureGate = gate
+	}
+}
+
+// applyFeatureGates sets the features from the configuration file or the
+// environment variable. Features given on the command line, the flags in fs,
+// are set when the flags are parsed.
+func (a *App) applyFeatureGates(fs *pflag.FlagSet) error {
+	if a.featureGate == nil || a.noConfig {
+		return nil
+	}
+	if source, _ := a.keySource(featuregate.FlagName, fs.Lookup(featuregate.FlagName)); source == SourceDefault || source == SourceFlag {
+		return nil
+	}
+
+	switch value := a.Viper().Get(featuregate.FlagName).(type) {
+	case string:
+		return a.featureGate.Set(value)
+	case map[string]interface{}:
+		features := make(map[string]bool, len(value))
+		for name, v := range value {
+			enabled, err := strconv.ParseBool(fmt.Sprint(v))
+			if err != nil {
+				return fmt.Errorf("invalid value of feature gate %s=%v, err: %w", name, v, err)
+			}
+			features[name] = enabled
+		}
+
+		return a.featureGate.SetFromMap(features)
+	default:
+		return fmt.Errorf("invalid value of %s: %v", featuregate.FlagName, value)
+	}
+}
+
+// reportFeatureGates reports the features whose state differs from their
+// default.
+func (a *App) reportFeatureGates(gate *featuregate.FeatureGate) {
+	var features []string
+	nonDefault := map[string]bool{}
+	for name, enabled := range gate.NonDefault() {
+		nonDefault[string(name)] = enabled
+		feature := fmt.Sprintf("%s=%t", name, enabled)
+		if spec, _ := gate.Spec(name); spec.PreRelease == featuregate.Deprecated {
+			feature += " (DEPRECATED)"
+		}
+		features = append(features, feature)
+	}
+	if len(features) > 0 {
+		sort.Strings(features)
+		a.report(EventFeatureGates, nonDefault, "Feature gates: %s", strings.Join(features, ", "))
+	}
+}
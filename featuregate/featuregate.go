@@ -0,0 +1,256 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+// Package featuregate implements feature gates, which switch features of an
+// application on and off by their maturity, e.g.
+//
+//	--feature-gates=Foo=true,Bar=false
+package featuregate
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/spf13/pflag"
+)
+
+// FlagName is the name of the flag which sets the feature gates, which is
+// also the configuration key.
+const FlagName = "feature-gates"
+
+// Feature is the name of a feature gate.
+type Feature string
+
+// Stage is the maturity of a feature.
+type Stage string
+
+// Define the maturity stages of features.
+const (
+	Alpha      Stage = "ALPHA"
+	Beta       Stage = "BETA"
+	GA         Stage = "GA"
+	Deprecated Stage = "DEPRECATED"
+)
+
+// FeatureSpec describes a feature gate.
+type FeatureSpec struct {
+	// Default is the default state of the feature.
+	Default bool
+	// PreRelease is the maturity of the feature.
+	PreRelease Stage
+	// LockToDefault prevents the feature from being switched away from its
+	// default, which is typical for GA features.
+	LockToDefault bool
+}
+
+// FeatureGate holds the known features and their states. It implements
+// pflag.Value, so that it can be set by the feature-gates flag.
+type FeatureGate struct {
+	mu      sync.RWMutex
+	known   map[Feature]FeatureSpec
+	enabled map[Feature]bool
+}
+
+// NewFeatureGate creates a feature gate without known features.
+func NewFeatureGate() *FeatureGate {
+	return &FeatureGate{
+		known:   map[Feature]FeatureSpec{},
+		enabled: map[Feature]bool{},
+	}
+}
+
+// Add registers the given features. It is an error to register a feature
+// twice with different specs.
+func (f *FeatureGate) Add(features map[Feature]FeatureSpec) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for name, spec := range features {
+		if existing, ok := f.known[name]; ok && existing != spec {
+			return fmt.Errorf("feature gate %q with different spec already exists: %v", name, existing)
+		}
+	}
+	for name, spec := range features {
+		f.known[name] = spec
+	}
+
+	return nil
+}
+
+// Set parses a comma-separated list of key=value pairs, e.g. "Foo=true,Bar=false",
+// and sets the features accordingly.
+func (f *FeatureGate) Set(value string) error {
+	m := map[string]bool{}
+	for _, s := range strings.Split(value, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(s, "=")
+		if !ok {
+			return fmt.Errorf("missing bool value for feature gate %s", k)
+		}
+		enabled, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("invalid value of feature gate %s=%s, err: %w", k, v, err)
+		}
+		m[strings.TrimSpace(k)] = enabled
+	}
+
+	return f.SetFromMap(m)
+}
+
+// SetFromMap sets the features from a map of names to states. Names are
+// matched case-insensitively, as configuration keys are lower-cased. Nothing
+// is set if a name is unknown or a feature locked to its default is switched.
+func (f *FeatureGate) SetFromMap(m map[string]bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	enabled := map[Feature]bool{}
+	for k, v := range m {
+		name, ok := f.lookup(k)
+		if !ok {
+			return fmt.Errorf("unrecognized feature gate: %s", k)
+		}
+		if spec := f.known[name]; spec.LockToDefault && spec.Default != v {
+			return fmt.Errorf("cannot set feature gate %s to %v, feature is locked to %v", name, v, spec.Default)
+		}
+		enabled[name] = v
+	}
+	for name, v := range enabled {
+		f.enabled[name] = v
+	}
+
+	return nil
+}
+
+// lookup returns the known feature matching name case-insensitively.
+func (f *FeatureGate) lookup(name string) (Feature, bool) {
+	if _, ok := f.known[Feature(name)]; ok {
+		return Feature(name), true
+	}
+	for known := range f.known {
+		if strings.EqualFold(string(known), name) {
+			return known, true
+		}
+	}
+
+	return "", false
+}
+
+// String returns the features set explicitly as a comma-separated list of
+// key=value pairs, sorted by name.
+func (f *FeatureGate) String() string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	pairs := make([]string, 0, len(f.enabled))
+	for name, v := range f.enabled {
+		pairs = append(pairs, fmt.Sprintf("%s=%t", name, v))
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ",")
+}
+
+// Type returns the type of the flag value.
+func (f *FeatureGate) Type() string {
+	return "mapStringBool"
+}
+
+// Enabled reports whether the feature is enabled. Unknown features are
+// disabled.
+func (f *FeatureGate) Enabled(name Feature) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if v, ok := f.enabled[name]; ok {
+		return v
+	}
+
+	return f.known[name].Default
+}
+
+// Spec returns the spec of the feature and whether it is known.
+func (f *FeatureGate) Spec(name Feature) (FeatureSpec, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	spec, ok := f.known[name]
+
+	return spec, ok
+}
+
+// NonDefault returns the features whose state differs from their default.
+func (f *FeatureGate) NonDefault() map[Feature]bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	features := map[Feature]bool{}
+	for name, v := range f.enabled {
+		if v != f.known[name].Default {
+			features[name] = v
+		}
+	}
+
+	return features
+}
+
+// KnownFeatures returns a description of every known feature, sorted by
+// name, e.g. "Foo=true|false (BETA - default=true)".
+func (f *FeatureGate) KnownFeatures() []string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	known := make([]string, 0, len(f.known))
+	for name, spec := range f.known {
+		stage := spec.PreRelease
+		if stage == "" {
+			stage = GA
+		}
+		if spec.LockToDefault {
+			known = append(known, fmt.Sprintf("%s=%t (%s - locked)", name, spec.Default, stage))
+
+			continue
+		}
+		known = append(known, fmt.Sprintf("%s=true|false (%s - default=%t)", name, stage, spec.Default))
+	}
+	sort.Strings(known)
+
+	return known
+}
+
+// AddFlag adds the feature-gates flag to fs.
+func (f *FeatureGate) AddFlag(fs *pflag.FlagSet) {
+	fs.Var(f, FlagName, "A set of key=value pairs that describe feature gates for alpha/experimental features. "+
+		"Options are:\n"+strings.Join(f.KnownFeatures(), "\n"))
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the feature gate.
+func NewContext(ctx context.Context, f *FeatureGate) context.Context {
+	return context.WithValue(ctx, contextKey{}, f)
+}
+
+// FromContext returns the feature gate carried by ctx, if any.
+func FromContext(ctx context.Context) (*FeatureGate, bool) {
+	f, ok := ctx.Value(contextKey{}).(*FeatureGate)
+
+	return f, ok
+}
+
+// Enabled reports whether the feature is enabled by the feature gate carried
+// by ctx. The feature is disabled if ctx carries no feature gate.
+func Enabled(ctx context.Context, name Feature) bool {
+	if f, ok := FromContext(ctx); ok {
+		return f.Enabled(name)
+	}
+
+	return false
+}
@@ -0,0 +1,88 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package flagvalue
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ByteSize is a number of bytes, written with an optional decimal (K, M, G,
+// T, P, E) or binary (Ki, Mi, Gi, Ti, Pi, Ei) unit suffix, optionally followed
+// by "B", e.g. "512Mi", "1.5G" or "4096".
+type ByteSize int64
+
+var byteUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"Ei", 1 << 60}, {"Pi", 1 << 50}, {"Ti", 1 << 40}, {"Gi", 1 << 30}, {"Mi", 1 << 20}, {"Ki", 1 << 10},
+	{"E", 1e18}, {"P", 1e15}, {"T", 1e12}, {"G", 1e9}, {"M", 1e6}, {"K", 1e3},
+}
+
+// ParseByteSize parses a byte size such as "512Mi".
+func ParseByteSize(s string) (ByteSize, error) {
+	num := strings.TrimSpace(s)
+	num = strings.TrimSuffix(strings.TrimSuffix(num, "B"), "b")
+	size := int64(1)
+	for _, unit := range byteUnits {
+		if strings.HasSuffix(strings.ToUpper(num), strings.ToUpper(unit.suffix)) {
+			num, size = num[:len(num)-len(unit.suffix)], unit.size
+
+			break
+		}
+	}
+
+	f, err := strconv.ParseFloat(strings.TrimSpace(num), 64)
+	// ParseFloat 接受 NaN 与 Inf，它们不是合法的字节数
+	if err != nil || f < 0 || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("invalid byte size %q", s)
+	}
+	bytes := f * float64(size)
+	// float64(math.MaxInt64) 会舍入为 2^63，因此使用 >= 比较
+	if bytes >= math.MaxInt64 {
+		return 0, fmt.Errorf("byte size %q is too large", s)
+	}
+
+	return ByteSize(bytes), nil
+}
+
+// Set parses the byte size.
+func (b *ByteSize) Set(s string) error {
+	size, err := ParseByteSize(s)
+	if err != nil {
+		return err
+	}
+	*b = size
+
+	return nil
+}
+
+// String returns the byte size with the largest unit that divides it, e.g.
+// "512Mi".
+func (b ByteSize) String() string {
+	if b == 0 {
+		return "0"
+	}
+	for _, unit := range byteUnits {
+		if int64(b)%unit.size == 0 {
+			return strconv.FormatInt(int64(b)/unit.size, 10) + unit.suffix
+		}
+	}
+
+	return strconv.FormatInt(int64(b), 10)
+}
+
+// Type returns the type of the flag value.
+func (b *ByteSize) Type() string {
+	return "size"
+}
+
+// Bytes returns the byte size as a number of bytes.
+func (b ByteSize) Bytes() int64 {
+	return int64(b)
+}
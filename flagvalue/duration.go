@@ -0,0 +1,59 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package flagvalue
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DurationRange is a range of durations written as MIN-MAX, e.g. "100ms-2s".
+type DurationRange struct {
+	Min time.Duration
+	Max time.Duration
+}
+
+// Set parses the duration range.
+func (r *DurationRange) Set(s string) error {
+	minimum, maximum, ok := strings.Cut(s, "-")
+	if !ok {
+		return fmt.Errorf("invalid duration range %q, must be MIN-MAX", s)
+	}
+	lo, err := time.ParseDuration(strings.TrimSpace(minimum))
+	if err != nil {
+		return fmt.Errorf("invalid duration range %q: %w", s, err)
+	}
+	hi, err := time.ParseDuration(strings.TrimSpace(maximum))
+	if err != nil {
+		return fmt.Errorf("invalid duration range %q: %w", s, err)
+	}
+	if lo > hi {
+		return fmt.Errorf("invalid duration range %q, minimum is greater than maximum", s)
+	}
+	r.Min, r.Max = lo, hi
+
+	return nil
+}
+
+// String returns the range as MIN-MAX, or an empty string if the range is
+// empty.
+func (r DurationRange) String() string {
+	if r.Min == 0 && r.Max == 0 {
+		return ""
+	}
+
+	return r.Min.String() + "-" + r.Max.String()
+}
+
+// Type returns the type of the flag value.
+func (r *DurationRange) Type() string {
+	return "durationRange"
+}
+
+// Contains reports whether d is within the range.
+func (r DurationRange) Contains(d time.Duration) bool {
+	return d >= r.Min && d <= r.Max
+}
@@ -0,0 +1,50 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package flagvalue
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Enum is a string restricted to a set of allowed values, which are listed
+// as the type of the flag in the help output.
+type Enum struct {
+	Value   string
+	Allowed []string
+}
+
+// NewEnum creates an enum with the default value def and the allowed values.
+func NewEnum(def string, allowed ...string) Enum {
+	return Enum{Value: def, Allowed: allowed}
+}
+
+// Set sets the enum to s if s is an allowed value.
+func (e *Enum) Set(s string) error {
+	for _, allowed := range e.Allowed {
+		if s == allowed {
+			e.Value = s
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("invalid value %q, must be one of: %s", s, strings.Join(e.Allowed, ", "))
+}
+
+// String returns the value of the enum.
+func (e Enum) String() string {
+	return e.Value
+}
+
+// Type returns the allowed values separated by "|".
+func (e *Enum) Type() string {
+	return strings.Join(e.Allowed, "|")
+}
+
+// AllowedValues returns the allowed values of the enum.
+func (e *Enum) AllowedValues() []string {
+	return e.Allowed
+}
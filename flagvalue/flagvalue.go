@@ -0,0 +1,99 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+// Package flagvalue provides pflag.Value implementations for common option
+// shapes, such as byte sizes, enums, address lists, URLs, existing paths, log
+// levels and TLS settings. Together with DecodeHook, the same types are
+// decoded from the configuration file by viper.Unmarshal, so that an option
+// has the same type whether it is set by a flag or by the configuration.
+package flagvalue
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/pflag"
+)
+
+// DecodeHook returns a mapstructure decode hook which decodes configuration
+// values into every type whose pointer implements pflag.Value, such as the
+// types of this package, by calling its Set method with the value formatted
+// as on the command line: lists are joined with commas and maps are written
+// as comma-separated key=value pairs. The current value of the target is
+// kept as the starting point, so that for example the allowed values of an
+// Enum are preserved.
+func DecodeHook() mapstructure.DecodeHookFuncValue {
+	return func(from reflect.Value, to reflect.Value) (interface{}, error) {
+		if !from.IsValid() || !to.IsValid() || from.Type() == to.Type() {
+			return from.Interface(), nil
+		}
+
+		target := reflect.New(to.Type())
+		value, ok := target.Interface().(pflag.Value)
+		if !ok {
+			return from.Interface(), nil
+		}
+		target.Elem().Set(to)
+		s := formatValue(from)
+		if s == value.String() {
+			// the default value of the flag, which may not be valid input
+			return to.Interface(), nil
+		}
+		if err := value.Set(s); err != nil {
+			return nil, err
+		}
+
+		return target.Elem().Interface(), nil
+	}
+}
+
+// formatValue formats a decoded configuration value in the command line
+// syntax.
+func formatValue(v reflect.Value) string {
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return string(v.Bytes())
+		}
+		items := make([]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			items = append(items, formatValue(v.Index(i)))
+		}
+
+		return strings.Join(items, ",")
+	case reflect.Map:
+		pairs := make([]string, 0, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			pairs = append(pairs, formatValue(iter.Key())+"="+formatValue(iter.Value()))
+		}
+		sort.Strings(pairs)
+
+		return strings.Join(pairs, ",")
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+// splitList splits a comma-separated list, ignoring empty items.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
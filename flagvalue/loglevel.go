@@ -0,0 +1,50 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package flagvalue
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// LogLevel is a log level: debug, info, warn or error, optionally followed by
+// an offset such as "info+2".
+type LogLevel slog.Level
+
+// Set parses the log level case-insensitively. "warning" is accepted as an
+// alias of "warn".
+func (l *LogLevel) Set(s string) error {
+	if strings.EqualFold(s, "warning") {
+		s = "warn"
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return fmt.Errorf("invalid log level %q, must be one of: debug, info, warn, error", s)
+	}
+	*l = LogLevel(level)
+
+	return nil
+}
+
+// String returns the lower-case name of the log level.
+func (l LogLevel) String() string {
+	return strings.ToLower(slog.Level(l).String())
+}
+
+// Type returns the type of the flag value.
+func (l *LogLevel) Type() string {
+	return "debug|info|warn|error"
+}
+
+// AllowedValues returns the names of the log levels.
+func (l *LogLevel) AllowedValues() []string {
+	return []string{"debug", "info", "warn", "error"}
+}
+
+// Level returns the log level as a slog.Level.
+func (l LogLevel) Level() slog.Level {
+	return slog.Level(l)
+}
@@ -0,0 +1,191 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package flagvalue
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// IPList is a comma-separated list of IP addresses.
+type IPList []net.IP
+
+// Set parses the list of IP addresses, replacing the current value.
+func (l *IPList) Set(s string) error {
+	var ips IPList
+	for _, item := range splitList(s) {
+		ip := net.ParseIP(item)
+		if ip == nil {
+			return fmt.Errorf("invalid IP address %q", item)
+		}
+		ips = append(ips, ip)
+	}
+	*l = ips
+
+	return nil
+}
+
+// String returns the comma-separated list of IP addresses.
+func (l IPList) String() string {
+	items := make([]string, 0, len(l))
+	for _, ip := range l {
+		items = append(items, ip.String())
+	}
+
+	return strings.Join(items, ",")
+}
+
+// Type returns the type of the flag value.
+func (l *IPList) Type() string {
+	return "ipList"
+}
+
+// CIDRList is a comma-separated list of networks in CIDR notation.
+type CIDRList []*net.IPNet
+
+// Set parses the list of networks, replacing the current value.
+func (l *CIDRList) Set(s string) error {
+	var nets CIDRList
+	for _, item := range splitList(s) {
+		_, ipNet, err := net.ParseCIDR(item)
+		if err != nil {
+			return fmt.Errorf("invalid CIDR %q", item)
+		}
+		nets = append(nets, ipNet)
+	}
+	*l = nets
+
+	return nil
+}
+
+// String returns the comma-separated list of networks.
+func (l CIDRList) String() string {
+	items := make([]string, 0, len(l))
+	for _, ipNet := range l {
+		items = append(items, ipNet.String())
+	}
+
+	return strings.Join(items, ",")
+}
+
+// Type returns the type of the flag value.
+func (l *CIDRList) Type() string {
+	return "cidrList"
+}
+
+// Contains reports whether one of the networks contains ip.
+func (l CIDRList) Contains(ip net.IP) bool {
+	for _, ipNet := range l {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// KeyValue is a comma-separated list of key=value pairs.
+type KeyValue map[string]string
+
+// Set parses the key=value pairs, replacing the current value.
+func (kv *KeyValue) Set(s string) error {
+	m := KeyValue{}
+	for _, item := range splitList(s) {
+		k, v, ok := strings.Cut(item, "=")
+		if !ok || k == "" {
+			return fmt.Errorf("invalid key=value pair %q", item)
+		}
+		m[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	*kv = m
+
+	return nil
+}
+
+// String returns the key=value pairs sorted by key.
+func (kv KeyValue) String() string {
+	pairs := make([]string, 0, len(kv))
+	for k, v := range kv {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ",")
+}
+
+// Type returns the type of the flag value.
+func (kv *KeyValue) Type() string {
+	return "keyValue"
+}
+
+// HostPort is a network address of the form host:port. The host may be empty
+// to listen on all interfaces.
+type HostPort struct {
+	Host string
+	Port uint16
+}
+
+// Set parses the host:port address.
+func (hp *HostPort) Set(s string) error {
+	host, port, err := net.SplitHostPort(s)
+	if err != nil {
+		return fmt.Errorf("invalid host:port address %q", s)
+	}
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid port in address %q", s)
+	}
+	hp.Host, hp.Port = host, uint16(p)
+
+	return nil
+}
+
+// String returns the address, or an empty string if no address is set.
+func (hp HostPort) String() string {
+	if hp.Host == "" && hp.Port == 0 {
+		return ""
+	}
+
+	return net.JoinHostPort(hp.Host, strconv.Itoa(int(hp.Port)))
+}
+
+// Type returns the type of the flag value.
+func (hp *HostPort) Type() string {
+	return "hostPort"
+}
+
+// URL is an absolute URL.
+type URL struct {
+	*url.URL
+}
+
+// Set parses the URL, which must have a scheme.
+func (u *URL) Set(s string) error {
+	parsed, err := url.Parse(s)
+	if err != nil || parsed.Scheme == "" {
+		return fmt.Errorf("invalid URL %q, must be absolute", s)
+	}
+	u.URL = parsed
+
+	return nil
+}
+
+// String returns the URL, or an empty string if no URL is set.
+func (u URL) String() string {
+	if u.URL == nil {
+		return ""
+	}
+
+	return u.URL.String()
+}
+
+// Type returns the type of the flag value.
+func (u *URL) Type() string {
+	return "url"
+}
@@ -0,0 +1,64 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package flagvalue
+
+import (
+	"fmt"
+	"os"
+)
+
+// ExistingFile is the path of an existing regular file.
+type ExistingFile string
+
+// Set sets the path if it is an existing file.
+func (f *ExistingFile) Set(s string) error {
+	info, err := os.Stat(s)
+	if err != nil {
+		return fmt.Errorf("file %q does not exist", s)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%q is a directory, not a file", s)
+	}
+	*f = ExistingFile(s)
+
+	return nil
+}
+
+// String returns the path.
+func (f ExistingFile) String() string {
+	return string(f)
+}
+
+// Type returns the type of the flag value.
+func (f *ExistingFile) Type() string {
+	return "file"
+}
+
+// ExistingDir is the path of an existing directory.
+type ExistingDir string
+
+// Set sets the path if it is an existing directory.
+func (d *ExistingDir) Set(s string) error {
+	info, err := os.Stat(s)
+	if err != nil {
+		return fmt.Errorf("directory %q does not exist", s)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%q is not a directory", s)
+	}
+	*d = ExistingDir(s)
+
+	return nil
+}
+
+// String returns the path.
+func (d ExistingDir) String() string {
+	return string(d)
+}
+
+// Type returns the type of the flag value.
+func (d *ExistingDir) Type() string {
+	return "dir"
+}
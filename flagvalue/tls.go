@@ -0,0 +1,108 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package flagvalue
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// TLSVersion is a TLS protocol version: 1.0, 1.1, 1.2 or 1.3. The names of
+// the crypto/tls constants, such as VersionTLS12, are accepted as well.
+type TLSVersion uint16
+
+// Set parses the TLS version.
+func (v *TLSVersion) Set(s string) error {
+	name := strings.TrimPrefix(strings.TrimPrefix(s, "VersionTLS"), "TLS")
+	if len(name) == 2 && !strings.Contains(name, ".") {
+		name = name[:1] + "." + name[1:]
+	}
+	version, ok := tlsVersions[name]
+	if !ok {
+		return fmt.Errorf("invalid TLS version %q, must be one of: 1.0, 1.1, 1.2, 1.3", s)
+	}
+	*v = TLSVersion(version)
+
+	return nil
+}
+
+// String returns the TLS version, e.g. "1.2", or an empty string if no
+// version is set.
+func (v TLSVersion) String() string {
+	for name, version := range tlsVersions {
+		if uint16(v) == version {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// Type returns the type of the flag value.
+func (v *TLSVersion) Type() string {
+	return "1.0|1.1|1.2|1.3"
+}
+
+// AllowedValues returns the TLS versions.
+func (v *TLSVersion) AllowedValues() []string {
+	return []string{"1.0", "1.1", "1.2", "1.3"}
+}
+
+// CipherSuites is a comma-separated list of TLS cipher suite names, as
+// returned by tls.CipherSuiteName.
+type CipherSuites []uint16
+
+// Set parses the list of cipher suites, replacing the current value.
+func (c *CipherSuites) Set(s string) error {
+	ids := map[string]uint16{}
+	for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		ids[suite.Name] = suite.ID
+	}
+
+	var suites CipherSuites
+	for _, name := range splitList(s) {
+		id, ok := ids[name]
+		if !ok {
+			return fmt.Errorf("unsupported cipher suite %q", name)
+		}
+		suites = append(suites, id)
+	}
+	*c = suites
+
+	return nil
+}
+
+// String returns the comma-separated list of cipher suite names.
+func (c CipherSuites) String() string {
+	names := make([]string, 0, len(c))
+	for _, id := range c {
+		names = append(names, tls.CipherSuiteName(id))
+	}
+
+	return strings.Join(names, ",")
+}
+
+// Type returns the type of the flag value.
+func (c *CipherSuites) Type() string {
+	return "cipherSuites"
+}
+
+// AllowedValues returns the names of the secure cipher suites.
+func (c *CipherSuites) AllowedValues() []string {
+	var names []string
+	for _, suite := range tls.CipherSuites() {
+		names = append(names, suite.Name)
+	}
+
+	return names
+}
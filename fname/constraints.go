@@ -0,0 +1,165 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package fname
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ConstraintKind is the kind of relationship between flags declared by a
+// Constraint.
+type ConstraintKind string
+
+// Define the kinds of constraints between flags.
+const (
+	// MutuallyExclusive allows at most one of the flags to be set.
+	MutuallyExclusive ConstraintKind = "mutually exclusive"
+	// ExactlyOne requires exactly one of the flags to be set.
+	ExactlyOne ConstraintKind = "exactly one"
+	// AllOrNone requires either all or none of the flags to be set.
+	AllOrNone ConstraintKind = "all or none"
+	// Required requires all of the flags to be set.
+	Required ConstraintKind = "required"
+	// Requires requires the Requires flags to be set when the first flag is
+	// set.
+	Requires ConstraintKind = "requires"
+)
+
+// Constraint declares a relationship between flags.
+type Constraint struct {
+	Kind ConstraintKind
+	// Flags are the names of the constrained flags.
+	Flags []string
+	// Requires are the names of the flags required by the first flag, for
+	// constraints of kind Requires.
+	Requires []string
+}
+
+// MarkMutuallyExclusive declares that at most one of the named flags may be
+// set.
+func (nfs *NamedFlagSets) MarkMutuallyExclusive(names ...string) {
+	nfs.Constraints = append(nfs.Constraints, Constraint{Kind: MutuallyExclusive, Flags: names})
+}
+
+// MarkExactlyOne declares that exactly one of the named flags must be set.
+func (nfs *NamedFlagSets) MarkExactlyOne(names ...string) {
+	nfs.Constraints = append(nfs.Constraints, Constraint{Kind: ExactlyOne, Flags: names})
+}
+
+// MarkAllOrNone declares that the named flags must be set together or not at
+// all.
+func (nfs *NamedFlagSets) MarkAllOrNone(names ...string) {
+	nfs.Constraints = append(nfs.Constraints, Constraint{Kind: AllOrNone, Flags: names})
+}
+
+// MarkRequired declares that the named flags must be set.
+func (nfs *NamedFlagSets) MarkRequired(names ...string) {
+	nfs.Constraints = append(nfs.Constraints, Constraint{Kind: Required, Flags: names})
+}
+
+// MarkRequires declares that the requires flags must be set when the named
+// flag is set.
+func (nfs *NamedFlagSets) MarkRequires(name string, requires ...string) {
+	nfs.Constraints = append(nfs.Constraints, Constraint{Kind: Requires, Flags: []string{name}, Requires: requires})
+}
+
+// Check checks every constraint, where isSet reports whether the named flag
+// is set. It returns an error for every violated constraint.
+func (nfs NamedFlagSets) Check(isSet func(name string) bool) []error {
+	var errs []error
+	for _, c := range nfs.Constraints {
+		if err := c.Check(isSet); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+// Check checks the constraint, where isSet reports whether the named flag is
+// set.
+func (c Constraint) Check(isSet func(name string) bool) error {
+	var set, unset []string
+	for _, name := range c.Flags {
+		if isSet(name) {
+			set = append(set, name)
+		} else {
+			unset = append(unset, name)
+		}
+	}
+
+	switch c.Kind {
+	case MutuallyExclusive:
+		if len(set) > 1 {
+			return fmt.Errorf("%s are mutually exclusive, but %s are set", flagList(c.Flags), flagList(set))
+		}
+	case ExactlyOne:
+		if len(set) == 0 {
+			return fmt.Errorf("exactly one of %s must be set", flagList(c.Flags))
+		}
+		if len(set) > 1 {
+			return fmt.Errorf("exactly one of %s must be set, but %s are set", flagList(c.Flags), flagList(set))
+		}
+	case AllOrNone:
+		if len(set) > 0 && len(unset) > 0 {
+			return fmt.Errorf("%s must be set together, but %s not set", flagList(c.Flags), isAre(unset))
+		}
+	case Required:
+		if len(unset) > 0 {
+			return fmt.Errorf("required %s not set", isAre(unset))
+		}
+	case Requires:
+		if len(set) == 0 {
+			return nil
+		}
+		var missing []string
+		for _, name := range c.Requires {
+			if !isSet(name) {
+				missing = append(missing, name)
+			}
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("%s requires %s to be set", flagList(c.Flags), flagList(missing))
+		}
+	}
+
+	return nil
+}
+
+// String describes the constraint for the help output.
+func (c Constraint) String() string {
+	switch c.Kind {
+	case MutuallyExclusive:
+		return fmt.Sprintf("%s are mutually exclusive", flagList(c.Flags))
+	case ExactlyOne:
+		return fmt.Sprintf("exactly one of %s is required", flagList(c.Flags))
+	case AllOrNone:
+		return fmt.Sprintf("%s must be set together", flagList(c.Flags))
+	case Required:
+		return fmt.Sprintf("%s required", isAre(c.Flags))
+	case Requires:
+		return fmt.Sprintf("%s requires %s", flagList(c.Flags), flagList(c.Requires))
+	default:
+		return fmt.Sprintf("%s: %s", c.Kind, flagList(c.Flags))
+	}
+}
+
+func flagList(names []string) string {
+	flags := make([]string, 0, len(names))
+	for _, name := range names {
+		flags = append(flags, "--"+name)
+	}
+
+	return strings.Join(flags, ", ")
+}
+
+func isAre(names []string) string {
+	if len(names) == 1 {
+		return flagList(names) + " is"
+	}
+
+	return flagList(names) + " are"
+}
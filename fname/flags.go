@@ -34,12 +34,19 @@ func WarnWordSepNormalizeFunc(f *pflag.FlagSet, name string) pflag.NormalizedNam
 // InitFlags normalizes, parses, then logs the command line flags.
 func InitFlags(flags *pflag.FlagSet) {
 	flags.SetNormalizeFunc(WordSepNormalizeFunc)
-	flags.AddGoFlagSet(flag.CommandLine)
+	// Add the go flags one by one instead of using AddGoFlagSet, which marks
+	// the process-global flag.CommandLine as parsed whenever flags is parsed.
+	flag.CommandLine.VisitAll(func(goflag *flag.Flag) {
+		if flags.Lookup(goflag.Name) == nil {
+			flags.AddFlag(pflag.PFlagFromGoFlag(goflag))
+		}
+	})
 }
 
-// PrintFlags logs the flags in the flagset.
+// PrintFlags logs the flags in the flagset. The values of sensitive flags are
+// redacted.
 func PrintFlags(flags *pflag.FlagSet) {
 	flags.VisitAll(func(flag *pflag.Flag) {
-		fmt.Printf("FLAG: --%s=%q\n", flag.Name, flag.Value)
+		fmt.Printf("FLAG: --%s=%q\n", flag.Name, FlagValue(flag))
 	})
 }
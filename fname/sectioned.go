@@ -19,6 +19,8 @@ type NamedFlagSets struct {
 	Order []string
 	// FlagSets stores the flag sets by name.
 	FlagSets map[string]*pflag.FlagSet
+	// Constraints are the relationships declared between the flags.
+	Constraints []Constraint
 }
 
 // FlagSet returns the flag set with the given name and adds it to the
@@ -35,8 +37,17 @@ func (nfs *NamedFlagSets) FlagSet(name string) *pflag.FlagSet {
 }
 
 // PrintSections prints the given names flag sets in sections, with the maximal given column number.
-// If cols is zero, lines are not wrapped.
+// If cols is zero, lines are not wrapped. The constraints are printed below the
+// section of their first flag.
 func PrintSections(w io.Writer, fss NamedFlagSets, cols int) {
+	PrintSectionsFunc(w, fss, cols, func(name string) string {
+		return SectionTitle(name) + ":"
+	})
+}
+
+// PrintSectionsFunc prints the given named flag sets like PrintSections, with
+// the heading of each section returned by heading, e.g. to color it.
+func PrintSectionsFunc(w io.Writer, fss NamedFlagSets, cols int, heading func(name string) string) {
 	for _, name := range fss.Order {
 		fs := fss.FlagSets[name]
 		if !fs.HasFlags() {
@@ -53,7 +64,7 @@ func PrintSections(w io.Writer, fss NamedFlagSets, cols int) {
 		}
 
 		var buf bytes.Buffer
-		fmt.Fprintf(&buf, "\n%s flags:\n\n%s", strings.ToUpper(name[:1])+name[1:], wideFS.FlagUsagesWrapped(cols))
+		fmt.Fprintf(&buf, "\n%s\n\n%s", heading(name), wideFS.FlagUsagesWrapped(cols))
 
 		if cols > 24 {
 			i := strings.Index(buf.String(), zzz)
@@ -63,5 +74,28 @@ func PrintSections(w io.Writer, fss NamedFlagSets, cols int) {
 		} else {
 			fmt.Fprint(w, buf.String())
 		}
+		printConstraints(w, fss.Constraints, fs)
+	}
+}
+
+// SectionTitle returns the title of the named section, e.g. "Mysql flags".
+// The section named "flags" is titled "Flags".
+func SectionTitle(name string) string {
+	if name == "" || strings.EqualFold(name, "flags") {
+		return "Flags"
+	}
+
+	return strings.ToUpper(name[:1]) + name[1:] + " flags"
+}
+
+func printConstraints(w io.Writer, constraints []Constraint, fs *pflag.FlagSet) {
+	var lines []string
+	for _, c := range constraints {
+		if len(c.Flags) > 0 && fs.Lookup(c.Flags[0]) != nil {
+			lines = append(lines, "    * "+c.String())
+		}
+	}
+	if len(lines) > 0 {
+		fmt.Fprintf(w, "\n  Constraints:\n%s\n", strings.Join(lines, "\n"))
 	}
 }
@@ -0,0 +1,42 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package fname
+
+import (
+	"github.com/spf13/pflag"
+)
+
+const (
+	// SensitiveAnnotation is the flag annotation which marks a flag as
+	// sensitive, so that its value is never printed.
+	SensitiveAnnotation = "sensitive"
+
+	// RedactedValue is printed in place of the value of a sensitive flag or
+	// option.
+	RedactedValue = "******"
+)
+
+// MarkSensitive marks the flag with the given name in fs as sensitive.
+func MarkSensitive(fs *pflag.FlagSet, name string) error {
+	return fs.SetAnnotation(name, SensitiveAnnotation, []string{"true"})
+}
+
+// IsSensitive reports whether the flag is marked as sensitive.
+func IsSensitive(flag *pflag.Flag) bool {
+	_, ok := flag.Annotations[SensitiveAnnotation]
+
+	return ok
+}
+
+// FlagValue returns the value of the flag as a string, or RedactedValue if the
+// flag is marked as sensitive and has a value.
+func FlagValue(flag *pflag.Flag) string {
+	value := flag.Value.String()
+	if IsSensitive(flag) && value != "" {
+		return RedactedValue
+	}
+
+	return value
+}
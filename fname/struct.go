@@ -0,0 +1,266 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package fname
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// Struct tags read by FromStruct.
+const (
+	// FlagTag names the flag of a field, e.g. `flag:"host"`. The flag names of
+	// nested structs are joined with dots, e.g. "mysql.host". A field tagged
+	// with `flag:"-"` is skipped, and `flag:",squash"` adds the fields of a
+	// nested struct to the parent. If the tag is empty, the lower-case field
+	// name is used, so that the flag names are also the configuration keys
+	// when the configuration is unmarshalled with FlagTag as tag name.
+	FlagTag = "flag"
+	// UsageTag is the usage message of the flag.
+	UsageTag = "usage"
+	// DefaultTag is the default value of the flag, in the syntax accepted on
+	// the command line. If the tag is empty, the current value of the field is
+	// the default.
+	DefaultTag = "default"
+	// ShortTag is the one-letter shorthand of the flag.
+	ShortTag = "short"
+	// SectionTag is the name of the flag set the flag is added to. Nested
+	// fields inherit the section of their parent. A top-level nested struct
+	// without section is added to the section named after its flag, other
+	// fields without section to the "generic" section.
+	SectionTag = "section"
+	// SensitiveTag marks the flag as sensitive, e.g. `sensitive:"true"`.
+	SensitiveTag = "sensitive"
+)
+
+const genericSection = "generic"
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	valueType    = reflect.TypeOf((*pflag.Value)(nil)).Elem()
+)
+
+// FromStruct builds the named flag sets of the options struct v points to.
+// Every supported exported field becomes a flag bound directly to the field,
+// described by the FlagTag, UsageTag, DefaultTag, ShortTag, SectionTag and
+// SensitiveTag struct tags. Nested structs and pointers to structs tagged with
+// FlagTag or SectionTag, and embedded structs, are walked recursively, nil
+// pointers are allocated. Other nested structs, such as a *tls.Config, are
+// skipped, as well as a struct nested in itself.
+//
+// Supported field types are strings, booleans, integers, unsigned integers,
+// floats, time.Duration, []string, []int, []time.Duration, map[string]string,
+// map[string]int and any type whose pointer implements pflag.Value. Untagged
+// fields of other types are skipped.
+//
+// FromStruct panics if v is not a non-nil pointer to a struct, if a field
+// tagged with FlagTag has an unsupported type, or if a default value cannot
+// be parsed, as these are programming errors.
+func FromStruct(v interface{}) NamedFlagSets {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("fname: FromStruct requires a non-nil pointer to a struct, got %T", v))
+	}
+
+	var fss NamedFlagSets
+	addStructFlags(&fss, rv.Elem(), "", "", map[reflect.Type]bool{})
+
+	return fss
+}
+
+func addStructFlags(fss *NamedFlagSets, v reflect.Value, prefix string, section string, parents map[reflect.Type]bool) {
+	t := v.Type()
+	parents[t] = true
+	defer delete(parents, t)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		tag, hasTag := field.Tag.Lookup(FlagTag)
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			continue
+		}
+		squash := opts == "squash" || (field.Anonymous && name == "")
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		fieldSection, hasSection := field.Tag.Lookup(SectionTag)
+		if fieldSection == "" {
+			fieldSection = section
+		}
+
+		fv := v.Field(i)
+		if isStruct(fv) {
+			// 只展开显式声明的嵌套结构体，并跳过自引用的结构体，避免无限递归
+			if (!hasTag && !hasSection && !squash) || parents[derefType(field.Type)] {
+				continue
+			}
+			if fv.Kind() == reflect.Ptr && fv.IsNil() {
+				fv.Set(reflect.New(field.Type.Elem()))
+			}
+			if squash {
+				addStructFlags(fss, reflect.Indirect(fv), prefix, fieldSection, parents)
+
+				continue
+			}
+			if fieldSection == "" && prefix == "" {
+				fieldSection = name
+			}
+			addStructFlags(fss, reflect.Indirect(fv), name, fieldSection, parents)
+
+			continue
+		}
+
+		if fieldSection == "" {
+			fieldSection = genericSection
+		}
+		// the default value is parsed by a flag defined on a scratch flag set,
+		// as the slice and map values of pflag would otherwise append the
+		// command line values to the default
+		scratch := pflag.NewFlagSet(name, pflag.ContinueOnError)
+		if !addFieldFlag(scratch, fv, name, "", "") {
+			if hasTag {
+				panic(fmt.Sprintf("fname: unsupported type %s of field %s.%s", field.Type, t.Name(), field.Name))
+			}
+
+			continue
+		}
+		if def, ok := field.Tag.Lookup(DefaultTag); ok {
+			if err := scratch.Set(name, def); err != nil {
+				panic(fmt.Sprintf("fname: invalid default value %q of flag %s: %v", def, name, err))
+			}
+		}
+
+		fs := fss.FlagSet(fieldSection)
+		addFieldFlag(fs, fv, name, field.Tag.Get(ShortTag), field.Tag.Get(UsageTag))
+		if sensitive, _ := strconv.ParseBool(field.Tag.Get(SensitiveTag)); sensitive {
+			_ = MarkSensitive(fs, name)
+		}
+	}
+}
+
+// isStruct reports whether v is a struct or a pointer to a struct, which is
+// walked for flags rather than being a flag itself.
+func isStruct(v reflect.Value) bool {
+	if v.CanAddr() && v.Addr().Type().Implements(valueType) {
+		return false
+	}
+	if v.Kind() == reflect.Ptr {
+		return v.Type().Elem().Kind() == reflect.Struct && !v.Type().Implements(valueType)
+	}
+
+	return v.Kind() == reflect.Struct
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t
+}
+
+// addFieldFlag adds a flag bound to the field v to fs and reports whether the
+// type of the field is supported.
+func addFieldFlag(fs *pflag.FlagSet, v reflect.Value, name, short, usage string) bool {
+	p := v.Addr().Interface()
+	if v.Kind() == reflect.Ptr && v.Type().Implements(valueType) {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		p = v.Interface()
+	}
+	if value, ok := p.(pflag.Value); ok {
+		fs.VarP(value, name, short, usage)
+
+		return true
+	}
+	if v.Type() == durationType {
+		fs.DurationVarP(p.(*time.Duration), name, short, v.Interface().(time.Duration), usage)
+
+		return true
+	}
+
+	switch p := p.(type) {
+	case *string:
+		fs.StringVarP(p, name, short, *p, usage)
+	case *bool:
+		fs.BoolVarP(p, name, short, *p, usage)
+	case *int:
+		fs.IntVarP(p, name, short, *p, usage)
+	case *int8:
+		fs.Int8VarP(p, name, short, *p, usage)
+	case *int16:
+		fs.Int16VarP(p, name, short, *p, usage)
+	case *int32:
+		fs.Int32VarP(p, name, short, *p, usage)
+	case *int64:
+		fs.Int64VarP(p, name, short, *p, usage)
+	case *uint:
+		fs.UintVarP(p, name, short, *p, usage)
+	case *uint8:
+		fs.Uint8VarP(p, name, short, *p, usage)
+	case *uint16:
+		fs.Uint16VarP(p, name, short, *p, usage)
+	case *uint32:
+		fs.Uint32VarP(p, name, short, *p, usage)
+	case *uint64:
+		fs.Uint64VarP(p, name, short, *p, usage)
+	case *float32:
+		fs.Float32VarP(p, name, short, *p, usage)
+	case *float64:
+		fs.Float64VarP(p, name, short, *p, usage)
+	case *[]string:
+		fs.StringSliceVarP(p, name, short, *p, usage)
+	case *[]int:
+		fs.IntSliceVarP(p, name, short, *p, usage)
+	case *[]time.Duration:
+		fs.DurationSliceVarP(p, name, short, *p, usage)
+	case *map[string]string:
+		fs.StringToStringVarP(p, name, short, *p, usage)
+	case *map[string]int:
+		fs.StringToIntVarP(p, name, short, *p, usage)
+	default:
+		return false
+	}
+
+	return true
+}
+
+// HasFlagTags reports whether the struct v is or points to, or one of its
+// nested structs, has a field tagged with FlagTag.
+func HasFlagTags(v interface{}) bool {
+	return hasFlagTags(reflect.TypeOf(v), map[reflect.Type]bool{})
+}
+
+func hasFlagTags(t reflect.Type, seen map[reflect.Type]bool) bool {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct || seen[t] {
+		return false
+	}
+
+	seen[t] = true
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if _, ok := field.Tag.Lookup(FlagTag); ok || hasFlagTags(field.Type, seen) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,56 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package fname
+
+import (
+	"crypto/tls"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+type node struct {
+	Name string `flag:"name"`
+	Next *node  `flag:"next"`
+}
+
+type structOptions struct {
+	Embedded
+	Server struct {
+		Port int `flag:"port"`
+	} `flag:"server"`
+	Node   *node        `flag:"node"`
+	TLS    *tls.Config  // 未声明标签的嵌套结构体不生成选项
+	Client *http.Client // 同上
+}
+
+type Embedded struct {
+	Debug bool `flag:"debug"`
+}
+
+func TestFromStructNested(t *testing.T) {
+	opts := &structOptions{}
+	fss := FromStruct(opts)
+
+	var names []string
+	for _, fs := range fss.FlagSets {
+		fs.VisitAll(func(flag *pflag.Flag) {
+			names = append(names, flag.Name)
+		})
+	}
+	sort.Strings(names)
+	if got, want := strings.Join(names, ","), "debug,node.name,server.port"; got != want {
+		t.Errorf("flags = %s, want %s", got, want)
+	}
+	if opts.TLS != nil || opts.Client != nil {
+		t.Error("untagged nested struct pointers were allocated")
+	}
+	if opts.Node == nil || opts.Node.Next != nil {
+		t.Errorf("Node = %+v, want an allocated node without next", opts.Node)
+	}
+}
@@ -5,12 +5,14 @@
 package app
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"strings"
 
-	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
+	"github.com/yuanbaopig/app/fname"
 )
 
 const (
@@ -52,6 +54,103 @@ func addHelpCommandFlag(usage string, fs *pflag.FlagSet) {
 		flagHelp,
 		flagHelpShorthand,
 		false,
-		fmt.Sprintf("Help for the %s command.", color.GreenString(strings.Split(usage, " ")[0])),
+		fmt.Sprintf("Help for the %s command.", strings.Split(usage, " ")[0]),
 	)
 }
+
+// setHelpFunc sets the help and usage functions of cmd, which are inherited
+// by all its sub commands, so that every level of the command tree prints
+// the same sectioned help.
+func (a *App) setHelpFunc(cmd *cobra.Command) {
+	cmd.SetUsageFunc(func(c *cobra.Command) error {
+		a.printUsage(c.OutOrStderr(), c)
+
+		return nil
+	})
+	cmd.SetHelpFunc(func(c *cobra.Command, _ []string) {
+		// 指定 --help-format=json 时以 JSON 格式输出整个命令行的说明
+		if a.printSpecIfRequested(c.OutOrStdout()) {
+			return
+		}
+		if desc := strings.TrimSpace(c.Long); desc != "" {
+			fmt.Fprintf(c.OutOrStdout(), "%s\n\n", desc)
+		} else if c.Short != "" {
+			fmt.Fprintf(c.OutOrStdout(), "%s\n\n", c.Short)
+		}
+		a.printUsage(c.OutOrStdout(), c)
+	})
+}
+
+// printUsage prints the usage of cmd: the usage lines, aliases, examples,
+// the sub commands by group and the flags by section, wrapped to the width
+// of the terminal.
+func (a *App) printUsage(w io.Writer, cmd *cobra.Command) {
+	heading := func(s string) string {
+		return a.paint(w, a.theme.Heading, "%s", s)
+	}
+	fmt.Fprintf(w, "%s", heading("Usage:"))
+	if cmd.Runnable() {
+		fmt.Fprintf(w, "\n  %s", a.paint(w, a.theme.Command, "%s", cmd.UseLine()))
+	}
+	if cmd.HasAvailableSubCommands() {
+		fmt.Fprintf(w, "\n  %s", a.paint(w, a.theme.Command, "%s [command]", cmd.CommandPath()))
+	}
+	fmt.Fprintln(w)
+	if len(cmd.Aliases) > 0 {
+		fmt.Fprintf(w, "\n%s\n  %s\n", heading("Aliases:"), cmd.NameAndAliases())
+	}
+	if cmd.HasExample() {
+		fmt.Fprintf(w, "\n%s\n%s\n", heading("Examples:"), cmd.Example)
+	}
+	if cmd.HasAvailableSubCommands() {
+		a.printCommands(w, cmd)
+	}
+
+	var sections bytes.Buffer
+	cols, _, _ := TerminalSize(w)
+	fname.PrintSectionsFunc(&sections, a.commandFlagSets(cmd), cols, func(name string) string {
+		return heading(fname.SectionTitle(name) + ":")
+	})
+	fmt.Fprint(w, a.paintFlags(w, sections.String()))
+
+	if cmd.HasAvailableSubCommands() {
+		fmt.Fprintf(w, "\nUse \"%s [command] --help\" for more information about a command.\n", cmd.CommandPath())
+	}
+}
+
+// printCommands prints the available sub commands of cmd, in the groups of
+// cmd if any. The commands outside of the groups are printed last.
+func (a *App) printCommands(w io.Writer, cmd *cobra.Command) {
+	var commands []*cobra.Command
+	padding := 0
+	for _, c := range cmd.Commands() {
+		if c.IsAvailableCommand() || c.Name() == "help" {
+			commands = append(commands, c)
+			if len(c.Name()) > padding {
+				padding = len(c.Name())
+			}
+		}
+	}
+	printGroup := func(title, groupID string) {
+		var lines []string
+		for _, c := range commands {
+			if c.GroupID == groupID {
+				lines = append(lines, fmt.Sprintf("  %s %s",
+					a.paint(w, a.theme.Command, "%-*s", padding, c.Name()), c.Short))
+			}
+		}
+		if len(lines) > 0 {
+			fmt.Fprintf(w, "\n%s\n%s\n", a.paint(w, a.theme.Heading, "%s", title), strings.Join(lines, "\n"))
+		}
+	}
+
+	if len(cmd.Groups()) == 0 {
+		printGroup("Available Commands:", "")
+
+		return
+	}
+	for _, group := range cmd.Groups() {
+		printGroup(group.Title, group.ID)
+	}
+	printGroup("Additional Commands:", "")
+}
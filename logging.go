@@ -0,0 +1,83 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"reflect"
+
+	"github.com/marmotedu/errors"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"github.com/yuanbaopig/app/fname"
+	"github.com/yuanbaopig/app/logging"
+)
+
+const loggingSection = "logging"
+
+// loggingOptions nests the logging options under the "log" flag prefix and
+// configuration key, e.g. --log.level and log.level.
+type loggingOptions struct {
+	Log *logging.Options `flag:"log" section:"logging"`
+}
+
+// Validate validates the logging options.
+func (o *loggingOptions) Validate() []error {
+	return o.Log.Validate()
+}
+
+// WithLogging adds the logging options to the application, as the "logging"
+// flag section, e.g. --log.level=debug and --log.format=json. The default
+// slog logger is configured from opts before the options of the application
+// are completed and validated, and its levels are updated when the
+// configuration is reloaded. Use logging.NewOptions for the defaults.
+func WithLogging(opts *logging.Options) Option {
+	return func(a *App) {
+		a.logging = opts
+	}
+}
+
+// addLoggingFlags adds the flags of the logging options to the logging
+// section of fss and to fs.
+func (a *App) addLoggingFlags(fss *fname.NamedFlagSets, fs *pflag.FlagSet) {
+	for _, f := range fname.FromStruct(&loggingOptions{Log: a.logging}).FlagSets {
+		fss.FlagSet(loggingSection).AddFlagSet(f)
+		fs.AddFlagSet(f)
+	}
+}
+
+// applyLogging unmarshals the logging options from the configuration, unless
+// disabled, and configures the default slog logger.
+func (a *App) applyLogging() error {
+	if a.logging == nil {
+		return nil
+	}
+	if !a.noConfig {
+		opts := &loggingOptions{Log: a.logging}
+		if err := a.Viper().Unmarshal(opts, decoderConfig(opts)); err != nil {
+			return err
+		}
+	}
+
+	return logging.Apply(a.logging)
+}
+
+// reloadLogging unmarshals the reloaded configuration v into a copy of the
+// logging options and validates it. The returned function configures the
+// default slog logger from the copy.
+func (a *App) reloadLogging(v *viper.Viper) (func() error, error) {
+	if a.logging == nil {
+		return func() error { return nil }, nil
+	}
+
+	opts := &loggingOptions{Log: deepCopy(reflect.ValueOf(a.logging)).Interface().(*logging.Options)}
+	if err := v.Unmarshal(opts, decoderConfig(opts)); err != nil {
+		return nil, err
+	}
+	if errs := opts.Validate(); len(errs) > 0 {
+		return nil, errors.NewAggregate(errs)
+	}
+
+	return func() error { return logging.Apply(opts.Log) }, nil
+}
@@ -0,0 +1,142 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"runtime"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/yuanbaopig/app/flagvalue"
+)
+
+// minLevel lets every record through the wrapped handler, which is filtered
+// by levelHandler instead.
+const minLevel = slog.Level(math.MinInt32)
+
+// moduleLevel is the minimum level of the logs of a package and its sub
+// packages.
+type moduleLevel struct {
+	pkg   string
+	level slog.Level
+}
+
+// parseVmodule parses package=level pairs, sorted by descending length of
+// the package, so that the longest matching prefix is found first.
+func parseVmodule(pairs []string) ([]moduleLevel, error) {
+	modules := make([]moduleLevel, 0, len(pairs))
+	for _, pair := range pairs {
+		pkg, s, ok := strings.Cut(pair, "=")
+		if !ok || pkg == "" {
+			return nil, fmt.Errorf("invalid --log.vmodule %q, must be package=level", pair)
+		}
+		var level flagvalue.LogLevel
+		if err := level.Set(s); err != nil {
+			return nil, fmt.Errorf("invalid --log.vmodule %q: %w", pair, err)
+		}
+		modules = append(modules, moduleLevel{pkg: pkg, level: level.Level()})
+	}
+	sort.SliceStable(modules, func(i, j int) bool {
+		return len(modules[i].pkg) > len(modules[j].pkg)
+	})
+
+	return modules, nil
+}
+
+// moduleLevels holds the per-package levels, which are replaced atomically
+// on reload.
+type moduleLevels struct {
+	p atomic.Pointer[[]moduleLevel]
+}
+
+func newModuleLevels() *moduleLevels {
+	m := &moduleLevels{}
+	m.set(nil)
+
+	return m
+}
+
+func (m *moduleLevels) set(modules []moduleLevel) {
+	m.p.Store(&modules)
+}
+
+func (m *moduleLevels) get() []moduleLevel {
+	return *m.p.Load()
+}
+
+// levelHandler filters the records by the global level, or the level of the
+// package of the caller if it has one.
+type levelHandler struct {
+	next    slog.Handler
+	level   *slog.LevelVar
+	modules *moduleLevels
+}
+
+// Enabled reports whether a record at the given level may be logged, which
+// is the case if it is at least the global level or the level of a package.
+func (h *levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if level >= h.level.Level() {
+		return true
+	}
+	for _, m := range h.modules.get() {
+		if level >= m.level {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Handle passes the record to the wrapped handler if its level is at least
+// the level of the package of the caller.
+func (h *levelHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level < h.levelOf(r.PC) {
+		return nil
+	}
+
+	return h.next.Handle(ctx, r)
+}
+
+// levelOf returns the level of the package of the function at pc.
+func (h *levelHandler) levelOf(pc uintptr) slog.Level {
+	modules := h.modules.get()
+	if len(modules) == 0 || pc == 0 {
+		return h.level.Level()
+	}
+
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	pkg := packageName(frame.Function)
+	for _, m := range modules {
+		if pkg == m.pkg || strings.HasPrefix(pkg, m.pkg+"/") {
+			return m.level
+		}
+	}
+
+	return h.level.Level()
+}
+
+// packageName returns the import path of the package of the fully qualified
+// function name, e.g. "github.com/foo/db" for "github.com/foo/db.(*DB).Query".
+func packageName(function string) string {
+	slash := strings.LastIndex(function, "/")
+	if dot := strings.Index(function[slash+1:], "."); dot >= 0 {
+		return function[:slash+1+dot]
+	}
+
+	return function
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{next: h.next.WithAttrs(attrs), level: h.level, modules: h.modules}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{next: h.next.WithGroup(name), level: h.level, modules: h.modules}
+}
@@ -0,0 +1,156 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+// Package logging configures the default log/slog logger of an application
+// from a group of options: the level, the format, the output paths with
+// size-based rotation and the per-package verbosity.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/marmotedu/errors"
+	"github.com/yuanbaopig/app/flagvalue"
+)
+
+// Define the log formats.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Define the special output paths.
+const (
+	Stdout = "stdout"
+	Stderr = "stderr"
+)
+
+// Options are the logging options. The struct tags describe their flags, see
+// fname.FromStruct.
+type Options struct {
+	Level       flagvalue.LogLevel `flag:"level" usage:"Minimum level of the logs."`
+	Format      flagvalue.Enum     `flag:"format" usage:"Format of the logs."`
+	OutputPaths []string           `flag:"output-paths" usage:"Outputs of the logs: stdout, stderr or file paths."`
+	MaxSize     flagvalue.ByteSize `flag:"max-size" usage:"Size at which a log file is rotated, e.g. 100Mi. 0 disables the rotation."`
+	MaxBackups  int                `flag:"max-backups" usage:"Number of rotated log files to keep."`
+	// Vmodule sets the minimum level of the logs of packages, as pairs of an
+	// import path and a level, e.g. "github.com/foo/db=debug". The level of
+	// the longest matching import path prefix applies.
+	Vmodule []string `flag:"vmodule" usage:"Per-package levels as comma-separated package=level pairs, e.g. github.com/foo/db=debug."`
+}
+
+// NewOptions creates the logging options with their defaults: info level,
+// text format to stderr, with files rotated at 100Mi and 3 backups.
+func NewOptions() *Options {
+	return &Options{
+		Level:       flagvalue.LogLevel(slog.LevelInfo),
+		Format:      flagvalue.NewEnum(FormatText, FormatText, FormatJSON),
+		OutputPaths: []string{Stderr},
+		MaxSize:     100 * flagvalue.ByteSize(1<<20),
+		MaxBackups:  3,
+	}
+}
+
+// Validate checks the logging options.
+func (o *Options) Validate() []error {
+	var errs []error
+	if len(o.OutputPaths) == 0 {
+		errs = append(errs, fmt.Errorf("--log.output-paths must not be empty"))
+	}
+	if o.MaxSize < 0 {
+		errs = append(errs, fmt.Errorf("--log.max-size must not be negative, got %s", o.MaxSize))
+	}
+	if o.MaxBackups < 0 {
+		errs = append(errs, fmt.Errorf("--log.max-backups must not be negative, got %d", o.MaxBackups))
+	}
+	if _, err := parseVmodule(o.Vmodule); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errs
+}
+
+var (
+	mu      sync.Mutex
+	outputs string
+	closers []io.Closer
+	level   = new(slog.LevelVar)
+	modules = newModuleLevels()
+)
+
+// Apply validates o and sets the default slog logger accordingly. The level
+// and the per-package levels of the current logger are updated in place,
+// the outputs are only reopened if the format, the output paths or the
+// rotation changed, so Apply can be called again on configuration reload.
+func Apply(o *Options) error {
+	if errs := o.Validate(); len(errs) > 0 {
+		return errors.NewAggregate(errs)
+	}
+	vmodule, _ := parseVmodule(o.Vmodule)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	level.Set(o.Level.Level())
+	modules.set(vmodule)
+
+	key := fmt.Sprintf("%s|%s|%d|%d", o.Format.Value, strings.Join(o.OutputPaths, ","), o.MaxSize, o.MaxBackups)
+	if key == outputs {
+		return nil
+	}
+	w, opened, err := openOutputs(o)
+	if err != nil {
+		return err
+	}
+
+	opts := &slog.HandlerOptions{Level: minLevel}
+	var handler slog.Handler = slog.NewTextHandler(w, opts)
+	if o.Format.Value == FormatJSON {
+		handler = slog.NewJSONHandler(w, opts)
+	}
+	slog.SetDefault(slog.New(&levelHandler{next: handler, level: level, modules: modules}))
+
+	for _, c := range closers {
+		_ = c.Close()
+	}
+	outputs, closers = key, opened
+
+	return nil
+}
+
+// openOutputs opens the output paths of o and returns the writer to all of
+// them, with the closers of the files.
+func openOutputs(o *Options) (io.Writer, []io.Closer, error) {
+	var writers []io.Writer
+	var opened []io.Closer
+	for _, path := range o.OutputPaths {
+		switch path {
+		case Stdout:
+			writers = append(writers, os.Stdout)
+		case Stderr:
+			writers = append(writers, os.Stderr)
+		default:
+			f, err := OpenRotatingFile(path, o.MaxSize.Bytes(), o.MaxBackups)
+			if err != nil {
+				for _, c := range opened {
+					_ = c.Close()
+				}
+
+				return nil, nil, err
+			}
+			writers = append(writers, f)
+			opened = append(opened, f)
+		}
+	}
+	if len(writers) == 1 {
+		return writers[0], opened, nil
+	}
+
+	return io.MultiWriter(writers...), opened, nil
+}
@@ -4,17 +4,30 @@
 
 package app
 
-import "github.com/yuanbaopig/app/fname"
+import (
+	"reflect"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/viper"
+	"github.com/yuanbaopig/app/flagvalue"
+	"github.com/yuanbaopig/app/fname"
+)
 
 // CliOptions abstracts configuration options for reading parameters from the
-// command line.
+// command line. The flags of options which do not implement FlaggedOptions are
+// built from the struct tags of the options by fname.FromStruct.
 type CliOptions interface {
-	Flags() (fss fname.NamedFlagSets)
 	Validate() []error
 	// AddFlags adds flags to the specified FlagSet object.
 	// AddFlags(fs *pflag.FlagSet)
 }
 
+// FlaggedOptions abstracts options which define their command line flags
+// themselves.
+type FlaggedOptions interface {
+	Flags() (fss fname.NamedFlagSets)
+}
+
 // ConfigurableOptions abstracts configuration options for reading parameters
 // from a configuration file.
 type ConfigurableOptions interface {
@@ -32,3 +45,63 @@ type CompletableOptions interface {
 type PrintableOptions interface {
 	String() string
 }
+
+// ReloadableOptions abstracts options which can be reloaded when the
+// configuration file changes.
+type ReloadableOptions interface {
+	// Reload is called on the running options with the previous and the newly
+	// loaded, completed and validated options.
+	Reload(old, new CliOptions) error
+}
+
+// optionsFlags returns the named flag sets of opts, built by fname.FromStruct
+// if opts does not implement FlaggedOptions.
+func optionsFlags(opts CliOptions) fname.NamedFlagSets {
+	if flagged, ok := opts.(FlaggedOptions); ok {
+		return flagged.Flags()
+	}
+
+	return fname.FromStruct(opts)
+}
+
+// optionsTagName returns the struct tag naming the configuration keys of the
+// fields of opts: fname.FlagTag if the flags are built from the struct tags by
+// fname.FromStruct, either implicitly or by a Flags method which declares
+// constraints on its result, mapstructure otherwise.
+func optionsTagName(opts CliOptions) string {
+	if _, ok := opts.(FlaggedOptions); ok && !fname.HasFlagTags(opts) {
+		return "mapstructure"
+	}
+
+	return fname.FlagTag
+}
+
+// decoderConfig configures viper.Unmarshal to decode the configuration into
+// opts by the tag returned by optionsTagName.
+func decoderConfig(opts CliOptions) viper.DecoderConfigOption {
+	tagName := optionsTagName(opts)
+
+	return func(c *mapstructure.DecoderConfig) {
+		c.TagName = tagName
+		c.Squash = tagName == fname.FlagTag
+		c.DecodeHook = mapstructure.ComposeDecodeHookFunc(
+			replaceCollectionsHookFunc(),
+			flagvalue.DecodeHook(),
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToSliceHookFunc(","),
+		)
+	}
+}
+
+// replaceCollectionsHookFunc returns a decode hook which empties slices and
+// maps before they are decoded, so that the configured value replaces the
+// default value of the flag instead of being merged into it.
+func replaceCollectionsHookFunc() mapstructure.DecodeHookFuncValue {
+	return func(from reflect.Value, to reflect.Value) (interface{}, error) {
+		if (to.Kind() == reflect.Slice || to.Kind() == reflect.Map) && to.CanSet() {
+			to.Set(reflect.Zero(to.Type()))
+		}
+
+		return from.Interface(), nil
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/yuanbaopig/app/fname"
+)
+
+// ConfigSource describes where the effective value of a configuration key
+// comes from.
+type ConfigSource string
+
+// Define the sources of configuration values, from the lowest to the highest
+// precedence.
+const (
+	SourceDefault ConfigSource = "default"
+	SourceConfig  ConfigSource = "config"
+	SourceEnv     ConfigSource = "env"
+	SourceFlag    ConfigSource = "flag"
+)
+
+// Provenance records the effective value of a configuration key and where it
+// comes from.
+type Provenance struct {
+	Key    string       `json:"key"`
+	Value  interface{}  `json:"value"`
+	Source ConfigSource `json:"source"`
+	// Origin is the configuration file path, the environment variable name or
+	// the flag name the value was read from. It is empty for defaults.
+	Origin string `json:"origin,omitempty"`
+	// Sensitive reports whether the value belongs to a flag or an option field
+	// marked as sensitive, in which case it must not be printed.
+	Sensitive bool `json:"sensitive,omitempty"`
+}
+
+// Provenance returns the provenance of every configuration key resolved by the
+// last execution of the application, indexed by key.
+func (a *App) Provenance() map[string]Provenance {
+	a.resolvedMu.RLock()
+	defer a.resolvedMu.RUnlock()
+
+	provenance := make(map[string]Provenance, len(a.provenance))
+	for k, v := range a.provenance {
+		provenance[k] = v
+	}
+
+	return provenance
+}
+
+// sortedProvenance returns the provenance of every resolved configuration key
+// sorted by key.
+func (a *App) sortedProvenance() []Provenance {
+	a.resolvedMu.RLock()
+	defer a.resolvedMu.RUnlock()
+
+	items := make([]Provenance, 0, len(a.provenance))
+	for _, item := range a.provenance {
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Key < items[j].Key })
+
+	return items
+}
+
+// resolveProvenance records where the effective value of every configuration
+// key but those of the builtin flags comes from, following the precedence used
+// by viper: flags set on the command line, then environment variables, then
+// the configuration file and finally the defaults.
+func (a *App) resolveProvenance(fs *pflag.FlagSet) {
+	flags := map[string]*pflag.Flag{}
+	fs.VisitAll(func(flag *pflag.Flag) {
+		flags[strings.ToLower(flag.Name)] = flag
+	})
+
+	v := a.Viper()
+	provenance := map[string]Provenance{}
+	for _, key := range v.AllKeys() {
+		// 与 config view 保持一致，不记录内置选项与 go flag 选项
+		if isBuiltinKey(key) {
+			continue
+		}
+		item := Provenance{
+			Key:       key,
+			Value:     v.Get(key),
+			Sensitive: a.isSensitiveKey(key),
+		}
+		flag, ok := flags[key]
+		if ok && fname.IsSensitive(flag) {
+			item.Sensitive = true
+		}
+		item.Source, item.Origin = a.keySource(key, flag)
+		provenance[key] = item
+	}
+
+	a.resolvedMu.Lock()
+	a.provenance = provenance
+	a.resolvedMu.Unlock()
+}
+
+// keySource returns where the effective value of the configuration key comes
+// from and its origin, given the flag bound to the key, if any.
+func (a *App) keySource(key string, flag *pflag.Flag) (ConfigSource, string) {
+	if flag != nil && flag.Changed {
+		return SourceFlag, "--" + flag.Name
+	}
+	if env := a.envVarName(key); os.Getenv(env) != "" {
+		return SourceEnv, env
+	}
+	if a.Viper().InConfig(key) {
+		return SourceConfig, a.configOrigin(key)
+	}
+
+	return SourceDefault, ""
+}
@@ -0,0 +1,185 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/yuanbaopig/app/fname"
+)
+
+// sensitiveTag is the struct tag which marks an option field as sensitive,
+// e.g. `sensitive:"true"`.
+const sensitiveTag = "sensitive"
+
+// addSensitiveOptions records the configuration keys of the fields of opts
+// which are tagged as sensitive and marks the matching flags in fs as
+// sensitive.
+func (a *App) addSensitiveOptions(opts CliOptions, fs *pflag.FlagSet) {
+	if opts == nil {
+		return
+	}
+
+	walkSensitiveFields(reflect.ValueOf(opts), optionsTagName(opts), nil, func(key string, _ reflect.Value) {
+		a.sensitiveKeys = append(a.sensitiveKeys, key)
+	})
+	fs.VisitAll(func(flag *pflag.Flag) {
+		if a.isSensitiveKey(strings.ToLower(flag.Name)) {
+			_ = fname.MarkSensitive(fs, flag.Name)
+		}
+	})
+}
+
+// isSensitiveKey reports whether the configuration key belongs to an option
+// field tagged as sensitive.
+func (a *App) isSensitiveKey(key string) bool {
+	for _, k := range a.sensitiveKeys {
+		if key == k || strings.HasPrefix(key, k+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// collectSensitiveFlags records the configuration keys of the sensitive flags
+// in fs, which include the flags marked as sensitive by the Flags method of
+// the options.
+func (a *App) collectSensitiveFlags(fs *pflag.FlagSet) {
+	keys := map[string]bool{}
+	fs.VisitAll(func(flag *pflag.Flag) {
+		if fname.IsSensitive(flag) {
+			keys[strings.ToLower(flag.Name)] = true
+		}
+	})
+
+	// 每次执行都重新收集，不保留上一次执行的敏感选项
+	a.resolvedMu.Lock()
+	a.sensitiveFlags = keys
+	a.resolvedMu.Unlock()
+}
+
+// redactedOptions returns a deep copy of opts in which the values of the
+// sensitive fields, tagged as sensitive or bound to a sensitive flag, are
+// replaced with fname.RedactedValue, or cleared if they are not strings. It is
+// used to print the options.
+func (a *App) redactedOptions(opts CliOptions) CliOptions {
+	a.resolvedMu.RLock()
+	keys := a.sensitiveFlags
+	a.resolvedMu.RUnlock()
+
+	c := deepCopy(reflect.ValueOf(opts))
+	walkSensitiveFields(c, optionsTagName(opts), keys, func(_ string, v reflect.Value) {
+		redactValue(v)
+	})
+
+	return c.Interface().(CliOptions)
+}
+
+// redactValue replaces the value of the settable v with fname.RedactedValue,
+// or clears it if it cannot hold a string.
+func redactValue(v reflect.Value) {
+	if !v.CanSet() || v.IsZero() {
+		return
+	}
+	switch {
+	case v.Kind() == reflect.String:
+		v.SetString(fname.RedactedValue)
+	case v.Kind() == reflect.Ptr:
+		redactValue(v.Elem())
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		for i := 0; i < v.Len(); i++ {
+			v.Index(i).SetString(fname.RedactedValue)
+		}
+	default:
+		v.Set(reflect.Zero(v.Type()))
+	}
+}
+
+// walkSensitiveFields calls fn with the configuration key and the value of
+// every field of v which is tagged as sensitive or whose key is in keys. The
+// configuration key of a field follows the naming used by viper.Unmarshal: the
+// name given by the tagName tag or the lower-case field name.
+func walkSensitiveFields(v reflect.Value, tagName string, keys map[string]bool, fn func(key string, v reflect.Value)) {
+	walkSensitive(v, "", tagName, keys, map[reflect.Type]bool{}, fn)
+}
+
+func walkSensitive(v reflect.Value, prefix, tagName string, keys map[string]bool, parents map[reflect.Type]bool, fn func(string, reflect.Value)) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			v = reflect.Zero(derefType(v.Type()))
+
+			break
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct || parents[v.Type()] {
+		return
+	}
+
+	t := v.Type()
+	parents[t] = true
+	defer delete(parents, t)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name, squash := fieldKey(field, tagName)
+		if name == "-" {
+			continue
+		}
+		key := prefix
+		if !squash {
+			key = joinKey(prefix, name)
+		}
+
+		if sensitive, _ := strconv.ParseBool(field.Tag.Get(sensitiveTag)); sensitive || keys[key] {
+			fn(key, v.Field(i))
+
+			continue
+		}
+		if derefType(field.Type).Kind() == reflect.Struct {
+			walkSensitive(v.Field(i), key, tagName, keys, parents, fn)
+		}
+	}
+}
+
+// fieldKey returns the configuration key segment of the struct field and
+// whether its fields are squashed into the parent. Embedded structs without
+// name are squashed when the options are described by fname.FlagTag.
+func fieldKey(field reflect.StructField, tagName string) (string, bool) {
+	name, opts, _ := strings.Cut(field.Tag.Get(tagName), ",")
+	squash := tagName == fname.FlagTag && field.Anonymous && name == ""
+	for _, opt := range strings.Split(opts, ",") {
+		if opt == "squash" {
+			squash = true
+		}
+	}
+	if name == "" {
+		name = field.Name
+	}
+
+	return strings.ToLower(name), squash
+}
+
+func joinKey(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+
+	return prefix + "." + name
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t
+}
@@ -0,0 +1,67 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/yuanbaopig/app/fname"
+)
+
+type printableOptions struct {
+	User string `flag:"user" usage:"User."`
+	PIN  string `flag:"pin" sensitive:"true" usage:"PIN."`
+	Code int    `flag:"code" usage:"Code, marked as sensitive by the flag."`
+}
+
+func (o *printableOptions) Validate() []error { return nil }
+
+func (o *printableOptions) Flags() fname.NamedFlagSets {
+	fss := fname.FromStruct(o)
+	_ = fname.MarkSensitive(fss.FlagSet("generic"), "code")
+
+	return fss
+}
+
+func (o *printableOptions) String() string {
+	return fmt.Sprintf("user=%s pin=%s code=%d", o.User, o.PIN, o.Code)
+}
+
+type eventRecorder struct {
+	events []Event
+}
+
+func (r *eventRecorder) Report(event Event) {
+	r.events = append(r.events, event)
+}
+
+func TestPrintableOptionsRedactsShortSecrets(t *testing.T) {
+	opts := &printableOptions{}
+	recorder := &eventRecorder{}
+	a := NewApp("test", "test", WithNoConfig(), WithOptions(opts), WithReporter(recorder),
+		WithIO(nil, io.Discard, io.Discard),
+		WithRunContextFunc(func(context.Context, []string, CliOptions) error {
+			return nil
+		}))
+	if code, err := a.Execute(context.Background(), []string{"--user=u12", "--pin=12", "--code=7"}); code != 0 || err != nil {
+		t.Fatalf("Execute() = %d, %v, want 0, nil", code, err)
+	}
+
+	var config string
+	for _, event := range recorder.events {
+		if event.Type == EventOptions {
+			config = fmt.Sprint(event.Data)
+		}
+	}
+	if want := "user=u12 pin=****** code=0"; config != want {
+		t.Errorf("printed options = %q, want %q", config, want)
+	}
+	if opts.PIN != "12" || opts.Code != 7 {
+		t.Errorf("options changed by the redaction: %+v", opts)
+	}
+}
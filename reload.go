@@ -0,0 +1,356 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"github.com/yuanbaopig/app/featuregate"
+)
+
+// WithConfigWatch enables hot reload of the configuration files. While the run
+// function is running, the configuration files are read again once they stop
+// changing and unmarshalled into a fresh copy of the options, which is then
+// completed and validated. A key removed from the files falls back to its
+// environment variable or default. Only if that succeeds the Reload method of
+// ReloadableOptions is called, otherwise, e.g. if a file is empty or cannot be
+// parsed, the previous configuration is kept and the error is reported.
+func WithConfigWatch() Option {
+	return func(a *App) {
+		a.configWatch = true
+	}
+}
+
+// callRunFunc calls run with the completed opts, watching the configuration
+// files for changes and serving the debug server while run is running if
+// enabled.
+func (a *App) callRunFunc(cmd *cobra.Command, run RunContextFunc, args []string, opts CliOptions) error {
+	// 没有 options 时仍然需要重新加载日志配置
+	if a.configWatch && !a.noConfig && (opts != nil || a.logging != nil) {
+		stop, err := a.watchConfig(cmd.Flags(), opts)
+		if err != nil {
+			return err
+		}
+		defer stop()
+	}
+
+	ctx := cmd.Context()
+	if a.featureGate != nil {
+		ctx = featuregate.NewContext(ctx, a.featureGate)
+	}
+	if a.debug != nil {
+		debugCtx, stop, err := a.startDebugServer(ctx, cmd.Flags())
+		if err != nil {
+			return err
+		}
+		defer stop()
+		ctx = debugCtx
+	}
+
+	return run(ctx, args, opts)
+}
+
+// configReloadDelay is how long the watcher waits for the configuration files
+// to stop changing before reloading them.
+const configReloadDelay = 100 * time.Millisecond
+
+// watchConfig watches the configuration files in use and the drop-in
+// directory, and reloads opts, bound to the flags in fs, when they change. The
+// directories of the files are watched rather than the files themselves, so
+// that atomic replacements and symlink swaps, such as those of Kubernetes
+// ConfigMap mounts, are detected as well. The returned function stops the
+// watcher.
+func (a *App) watchConfig(fs *pflag.FlagSet, opts CliOptions) (func(), error) {
+	a.resolvedMu.RLock()
+	files := append([]string(nil), a.configFilesUsed...)
+	dropInDir := a.configDropInDir
+	lastGood := a.configContent
+	a.resolvedMu.RUnlock()
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	dirs := map[string]bool{}
+	if dropInDir != "" {
+		dirs[filepath.Clean(dropInDir)] = true
+	}
+	for _, file := range files {
+		dirs[filepath.Dir(filepath.Clean(file))] = true
+	}
+	for dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			_ = watcher.Close()
+
+			return nil, err
+		}
+	}
+
+	realFiles := resolveSymlinks(files)
+	current := opts
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		var reload <-chan time.Time
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				currentFiles := resolveSymlinks(files)
+				// a config file or the drop-in directory was changed, or the
+				// real path of a config file changed (e.g. a ConfigMap replacement)
+				if !isConfigEvent(event, files, dropInDir) && reflect.DeepEqual(currentFiles, realFiles) {
+					continue
+				}
+				realFiles = currentFiles
+				// 编辑器可能分多次写入配置文件，例如先清空再写入，等待文件不再变化后再重新加载
+				reload = time.After(configReloadDelay)
+			case <-reload:
+				reload = nil
+				fresh, content, err := a.reloadConfig(fs, opts, current, lastGood)
+				if err != nil {
+					// the configuration of the last successful load is kept
+					fmt.Fprintf(a.errOut, "%v failed to reload configuration: %v\n", a.paint(a.errOut, a.theme.Error, "Error:"), err)
+
+					continue
+				}
+				if content == nil {
+					continue
+				}
+				current, lastGood = fresh, content
+				files := a.ConfigFilesUsed()
+				a.report(EventConfigReloaded, files, "Config file reloaded: `%s`", strings.Join(files, ", "))
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				fmt.Fprintf(a.errOut, "%v failed to watch configuration: %v\n", a.paint(a.errOut, a.theme.Error, "Error:"), err)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		_ = watcher.Close()
+		wg.Wait()
+	}, nil
+}
+
+// isConfigEvent reports whether event writes or creates one of the config
+// files, or changes the content of the drop-in directory.
+func isConfigEvent(event fsnotify.Event, files []string, dropInDir string) bool {
+	name := filepath.Clean(event.Name)
+	if dropInDir != "" && filepath.Dir(name) == filepath.Clean(dropInDir) {
+		return true
+	}
+	for _, file := range files {
+		if name == filepath.Clean(file) && (event.Has(fsnotify.Write) || event.Has(fsnotify.Create)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func resolveSymlinks(files []string) []string {
+	resolved := make([]string, len(files))
+	for i, file := range files {
+		resolved[i], _ = filepath.EvalSymlinks(file)
+	}
+
+	return resolved
+}
+
+// reloadConfig merges the configuration files again into a fresh viper
+// instance and unmarshals it into a copy of the options as they were before
+// the configuration was first unmarshalled into them, so that the keys removed
+// from the files, including those without flag, fall back to their default.
+// The copy is completed and validated before the Reload method of the running
+// opts, if any, is called and the logging options are reloaded. Only then the
+// fresh viper instance replaces the one of the application. Empty files are
+// rejected, since they are usually being written. It returns nil content if
+// the merged configuration equals lastGood.
+func (a *App) reloadConfig(fs *pflag.FlagSet, opts CliOptions, current CliOptions, lastGood []byte) (CliOptions, []byte, error) {
+	cfg, err := a.mergeConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(cfg.emptyFiles) > 0 {
+		return nil, nil, fmt.Errorf("configuration file(%s) is empty", strings.Join(cfg.emptyFiles, ", "))
+	}
+	if bytes.Equal(cfg.content, lastGood) {
+		return nil, nil, nil
+	}
+	v, err := a.newViper(fs, cfg.content)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var fresh CliOptions
+	if opts != nil {
+		base := a.pristineOptions
+		if base == nil {
+			base = current
+		}
+		if fresh, err = cloneOptions(base); err != nil {
+			return nil, nil, err
+		}
+		if err := v.Unmarshal(fresh, decoderConfig(fresh)); err != nil {
+			return nil, nil, err
+		}
+		if err := a.completeOptions(fresh); err != nil {
+			return nil, nil, err
+		}
+	}
+	applyLogging, err := a.reloadLogging(v)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if reloadable, ok := opts.(ReloadableOptions); ok {
+		if err := reloadable.Reload(current, fresh); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := applyLogging(); err != nil {
+		return nil, nil, err
+	}
+	a.setViper(v)
+	a.storeConfig(cfg)
+	a.resolveProvenance(fs)
+
+	return fresh, cfg.content, nil
+}
+
+// newViper returns a viper instance holding the YAML configuration content,
+// reading the environment variables and bound to the flags in fs like the one
+// of the application. The flags not set on the command line are bound to their
+// default value rather than to their current value, which is the one resolved
+// at startup, so that a key removed from the configuration falls back to its
+// default.
+func (a *App) newViper(fs *pflag.FlagSet, content []byte) (*viper.Viper, error) {
+	v := viper.New()
+	a.configureEnv(v)
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(bytes.NewReader(content)); err != nil {
+		return nil, err
+	}
+
+	var err error
+	fs.VisitAll(func(flag *pflag.Flag) {
+		if err != nil {
+			return
+		}
+		if flag.Changed {
+			err = v.BindPFlag(flag.Name, flag)
+		} else {
+			err = v.BindFlagValue(flag.Name, defaultFlag{flag})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// defaultFlag is a flag not set on the command line, which reports its
+// default value to viper.
+type defaultFlag struct {
+	flag *pflag.Flag
+}
+
+func (f defaultFlag) HasChanged() bool    { return false }
+func (f defaultFlag) Name() string        { return f.flag.Name }
+func (f defaultFlag) ValueString() string { return f.flag.DefValue }
+func (f defaultFlag) ValueType() string   { return f.flag.Value.Type() }
+
+// cloneOptions returns a deep copy of opts, which must be a non-nil pointer.
+// Unexported fields are copied shallowly.
+func cloneOptions(opts CliOptions) (CliOptions, error) {
+	v := reflect.ValueOf(opts)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return nil, fmt.Errorf("options of type %T must be a non-nil pointer to be reloaded", opts)
+	}
+
+	return deepCopy(v).Interface().(CliOptions), nil
+}
+
+func deepCopy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(deepCopy(v.Elem()))
+
+		return c
+	case reflect.Interface:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(deepCopy(v.Elem()))
+
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if c.Field(i).CanSet() {
+				c.Field(i).Set(deepCopy(v.Field(i)))
+			}
+		}
+
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i)))
+		}
+
+		return c
+	case reflect.Array:
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i)))
+		}
+
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			c.SetMapIndex(iter.Key(), deepCopy(iter.Value()))
+		}
+
+		return c
+	default:
+		return v
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yuanbaopig/app/logging"
+)
+
+type reloadOptions struct {
+	Name string `flag:"name" usage:"Name."`
+	Port int    `flag:"port" default:"1" usage:"Port."`
+	// Extra has no flag, its keys are only read from the configuration.
+	Extra struct {
+		Mode string
+	}
+	reloads chan string
+}
+
+func (o *reloadOptions) Validate() []error { return nil }
+
+func (o *reloadOptions) Reload(old, new CliOptions) error {
+	o.reloads <- fmt.Sprintf("%d->%d", old.(*reloadOptions).Port, new.(*reloadOptions).Port)
+
+	return nil
+}
+
+// syncBuffer is a buffer written by the config watcher and read by the test.
+type syncBuffer struct {
+	mu sync.Mutex
+	sb strings.Builder
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.sb.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.sb.String()
+}
+
+func writeConfig(t *testing.T, file string, content string) {
+	t.Helper()
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReloadRemovedKey(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "test.yaml")
+	writeConfig(t, file, "port: 5\n")
+	opts := &reloadOptions{reloads: make(chan string, 10)}
+	a := NewApp("test", "test", WithSilence(), WithOptions(opts), WithIO(nil, io.Discard, io.Discard),
+		WithRunContextFunc(func(context.Context, []string, CliOptions) error {
+			return nil
+		}))
+	if code, err := a.Execute(context.Background(), []string{"-c", file}); code != 0 || err != nil {
+		t.Fatalf("Execute() = %d, %v, want 0, nil", code, err)
+	}
+
+	fs := a.Command().Flags()
+	var current CliOptions = opts
+	lastGood := a.configContent
+	for _, tt := range []struct {
+		content string
+		want    string
+	}{
+		{"port: 7\n", "5->7"},
+		// 删除配置项后恢复为默认值，而不是启动时的值
+		{"name: test\n", "7->1"},
+	} {
+		writeConfig(t, file, tt.content)
+		fresh, content, err := a.reloadConfig(fs, opts, current, lastGood)
+		if err != nil {
+			t.Fatalf("reloadConfig(%q) failed: %v", tt.content, err)
+		}
+		if got := <-opts.reloads; got != tt.want {
+			t.Errorf("reloadConfig(%q) reloaded %s, want %s", tt.content, got, tt.want)
+		}
+		current, lastGood = fresh, content
+	}
+	if port := a.Viper().GetInt("port"); port != 1 {
+		t.Errorf("Viper().GetInt(port) = %d after the reload, want 1", port)
+	}
+
+	writeConfig(t, file, "")
+	if _, _, err := a.reloadConfig(fs, opts, current, lastGood); err == nil {
+		t.Error("reloadConfig() of an empty file succeeded, want an error")
+	}
+	writeConfig(t, file, "port: [")
+	if _, _, err := a.reloadConfig(fs, opts, current, lastGood); err == nil {
+		t.Error("reloadConfig() of an invalid file succeeded, want an error")
+	}
+	if port := a.Viper().GetInt("port"); port != 1 {
+		t.Errorf("Viper().GetInt(port) = %d after failed reloads, want 1", port)
+	}
+}
+
+func TestReloadRemovedConfigOnlyKey(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "test.yaml")
+	writeConfig(t, file, "extra:\n  mode: fast\n")
+	opts := &reloadOptions{reloads: make(chan string, 10)}
+	opts.Extra.Mode = "default"
+	a := NewApp("test", "test", WithSilence(), WithOptions(opts), WithConfigWatch(), WithIO(nil, io.Discard, io.Discard),
+		WithRunContextFunc(func(context.Context, []string, CliOptions) error {
+			return nil
+		}))
+	if code, err := a.Execute(context.Background(), []string{"-c", file}); code != 0 || err != nil {
+		t.Fatalf("Execute() = %d, %v, want 0, nil", code, err)
+	}
+	if opts.Extra.Mode != "fast" {
+		t.Fatalf("Extra.Mode = %q, want fast", opts.Extra.Mode)
+	}
+
+	writeConfig(t, file, "name: test\n")
+	fresh, _, err := a.reloadConfig(a.Command().Flags(), opts, opts, a.configContent)
+	if err != nil {
+		t.Fatalf("reloadConfig() failed: %v", err)
+	}
+	<-opts.reloads
+	if mode := fresh.(*reloadOptions).Extra.Mode; mode != "default" {
+		t.Errorf("Extra.Mode = %q after the key was removed, want default", mode)
+	}
+}
+
+func TestWatchConfigPartialWrite(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "test.yaml")
+	writeConfig(t, file, "port: 5\n")
+	opts := &reloadOptions{reloads: make(chan string, 10)}
+	errOut := &syncBuffer{}
+	a := NewApp("test", "test", WithSilence(), WithOptions(opts), WithConfigWatch(), WithIO(nil, io.Discard, errOut),
+		WithRunContextFunc(func(context.Context, []string, CliOptions) error {
+			// 模拟编辑器先清空文件再写入新的内容
+			writeConfig(t, file, "")
+			time.Sleep(configReloadDelay / 4)
+			writeConfig(t, file, "port: 7\n")
+
+			select {
+			case got := <-opts.reloads:
+				if got != "5->7" {
+					t.Errorf("reloaded %s, want 5->7", got)
+				}
+			case <-time.After(5 * time.Second):
+				t.Error("configuration not reloaded")
+			}
+			select {
+			case got := <-opts.reloads:
+				t.Errorf("reloaded %s again, want a single reload", got)
+			case <-time.After(2 * configReloadDelay):
+			}
+
+			return nil
+		}))
+	if code, err := a.Execute(context.Background(), []string{"-c", file}); code != 0 || err != nil {
+		t.Fatalf("Execute() = %d, %v, want 0, nil", code, err)
+	}
+	if errOut.String() != "" {
+		t.Errorf("unexpected errors: %s", errOut.String())
+	}
+}
+
+func TestWatchConfigLoggingOnly(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "test.yaml")
+	writeConfig(t, file, "log:\n  level: info\n")
+	a := NewApp("test", "test", WithSilence(), WithLogging(logging.NewOptions()), WithConfigWatch(),
+		WithIO(nil, io.Discard, io.Discard),
+		WithRunContextFunc(func(ctx context.Context, _ []string, opts CliOptions) error {
+			if slog.Default().Enabled(ctx, slog.LevelDebug) {
+				t.Fatal("debug logs enabled before the reload")
+			}
+			// 只有日志选项时同样需要监听配置文件
+			writeConfig(t, file, "log:\n  level: debug\n")
+
+			deadline := time.Now().Add(5 * time.Second)
+			for !slog.Default().Enabled(ctx, slog.LevelDebug) {
+				if time.Now().After(deadline) {
+					t.Fatal("log level not reloaded without options")
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			return nil
+		}))
+	if code, err := a.Execute(context.Background(), []string{"-c", file}); code != 0 || err != nil {
+		t.Fatalf("Execute() = %d, %v, want 0, nil", code, err)
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gosuri/uitable"
+	"github.com/yuanbaopig/app/fname"
+)
+
+// EventType identifies the startup information reported by the application.
+type EventType string
+
+// Define the events reported by the application.
+const (
+	// EventConfigFiles reports the configuration files used, Data is []string.
+	EventConfigFiles EventType = "config_files"
+	// EventConfigItems reports the resolved configuration, Data is
+	// []Provenance with the sensitive values redacted.
+	EventConfigItems EventType = "config_items"
+	// EventWorkingDir reports the working directory, Data is a string.
+	EventWorkingDir EventType = "working_dir"
+	// EventFlags reports the flags set or changed, Data is []FlagItem.
+	EventFlags EventType = "flags"
+	// EventStarting reports that the application starts, Data is its name.
+	EventStarting EventType = "starting"
+	// EventVersion reports the version, Data is version.Info.
+	EventVersion EventType = "version"
+	// EventFeatureGates reports the features whose state differs from their
+	// default, Data is map[string]bool.
+	EventFeatureGates EventType = "feature_gates"
+	// EventOptions reports the options of PrintableOptions, Data is the
+	// redacted string.
+	EventOptions EventType = "options"
+	// EventConfigReloaded reports the configuration files reloaded, Data is
+	// []string.
+	EventConfigReloaded EventType = "config_reloaded"
+	// EventDebugServer reports the address the debug server listens on, Data
+	// is a string.
+	EventDebugServer EventType = "debug_server"
+)
+
+// Event is a piece of startup information reported by the application.
+type Event struct {
+	Type EventType
+	// Message is the human-readable line of the event, e.g. "WorkingDir: /app".
+	Message string
+	// Data is the structured payload of the event, whose type depends on Type.
+	Data interface{}
+}
+
+// FlagItem is a flag which is set or differs from its default.
+type FlagItem struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Reporter reports the startup information of the application, such as the
+// configuration files used and the resolved configuration.
+type Reporter interface {
+	Report(event Event)
+}
+
+// WithReporter sets the reporter of the startup information, which is the
+// human-readable text written to the standard output by default.
+func WithReporter(r Reporter) Option {
+	return func(a *App) {
+		a.reporter = r
+	}
+}
+
+type nopReporter struct{}
+
+func (nopReporter) Report(Event) {}
+
+// NopReporter returns a reporter which discards all events, as set by
+// WithSilence.
+func NopReporter() Reporter {
+	return nopReporter{}
+}
+
+type textReporter struct {
+	w      io.Writer
+	marker func() string
+}
+
+// NewTextReporter returns a reporter which writes the events to w as
+// human-readable lines prefixed with "==>".
+func NewTextReporter(w io.Writer) Reporter {
+	return &textReporter{w: w, marker: func() string { return "==>" }}
+}
+
+// Report writes the message of the event, followed by the table of the
+// configuration items or the list of the flags.
+func (r *textReporter) Report(event Event) {
+	fmt.Fprintf(r.w, "%v %s\n", r.marker(), event.Message)
+	switch data := event.Data.(type) {
+	case []Provenance:
+		table := uitable.New()
+		table.Separator = " "
+		table.MaxColWidth = 80
+		table.AddRow("KEY", "VALUE", "SOURCE", "ORIGIN")
+		for _, item := range data {
+			table.AddRow(item.Key, item.Value, item.Source, item.Origin)
+		}
+		fmt.Fprintf(r.w, "%v\n", table)
+	case []FlagItem:
+		for _, flag := range data {
+			fmt.Fprintf(r.w, "FLAG: --%s=%q\n", flag.Name, flag.Value)
+		}
+	}
+}
+
+type jsonReporter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONReporter returns a reporter which writes the events to w as JSON
+// lines, e.g. {"time":"...","event":"working_dir","message":"...","data":"/app"}.
+// w is typically os.Stderr, so that the standard output of the application
+// is left to its own output.
+func NewJSONReporter(w io.Writer) Reporter {
+	return &jsonReporter{enc: json.NewEncoder(w)}
+}
+
+// Report writes the event as a JSON line.
+func (r *jsonReporter) Report(event Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_ = r.enc.Encode(struct {
+		Time    time.Time   `json:"time"`
+		Event   EventType   `json:"event"`
+		Message string      `json:"message"`
+		Data    interface{} `json:"data,omitempty"`
+	}{time.Now(), event.Type, event.Message, event.Data})
+}
+
+type slogReporter struct {
+	logger *slog.Logger
+}
+
+// NewSlogReporter returns a reporter which logs the events at info level to
+// logger, with the event type and data as attributes.
+func NewSlogReporter(logger *slog.Logger) Reporter {
+	return &slogReporter{logger: logger}
+}
+
+// Report logs the event.
+func (r *slogReporter) Report(event Event) {
+	r.logger.LogAttrs(context.Background(), slog.LevelInfo, event.Message,
+		slog.String("event", string(event.Type)), slog.Any("data", event.Data))
+}
+
+// report reports the event to the reporter of the application.
+func (a *App) report(eventType EventType, data interface{}, format string, args ...interface{}) {
+	a.reporter.Report(Event{Type: eventType, Message: fmt.Sprintf(format, args...), Data: data})
+}
+
+// redactedProvenance returns a copy of items with the sensitive values
+// replaced with RedactedValue.
+func redactedProvenance(items []Provenance) []Provenance {
+	redacted := make([]Provenance, len(items))
+	for i, item := range items {
+		if item.Sensitive {
+			item.Value = fname.RedactedValue
+		}
+		redacted[i] = item
+	}
+
+	return redacted
+}
@@ -0,0 +1,313 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/marmotedu/errors"
+)
+
+// Runnable is a component of the application, such as an HTTP server, a gRPC
+// server or a background worker, which runs concurrently with the other
+// runnables and the run function.
+type Runnable interface {
+	// Start runs the component until ctx is cancelled, which is how the
+	// component is stopped, or until it fails. A nil error returned before ctx
+	// is cancelled means the component has completed its work.
+	Start(ctx context.Context) error
+}
+
+// RunnableFunc adapts an ordinary function to a Runnable.
+type RunnableFunc func(ctx context.Context) error
+
+// Start calls f(ctx).
+func (f RunnableFunc) Start(ctx context.Context) error {
+	return f(ctx)
+}
+
+// ReadyRunnable is a runnable which takes time to become ready, e.g. to
+// connect to a database. The runnables registered after it, or depending on
+// it, are only started once it is ready.
+type ReadyRunnable interface {
+	Runnable
+	// WaitReady blocks until the runnable is ready, or until ctx is cancelled
+	// because the runnable or another one failed.
+	WaitReady(ctx context.Context) error
+}
+
+// namedRunnable is a runnable with a name and the names of the runnables it
+// depends on.
+type namedRunnable struct {
+	Runnable
+	name      string
+	dependsOn []string
+}
+
+// NewRunnable names r, so that other runnables can depend on it, and declares
+// the names of the runnables r depends on, which are started before it and
+// stopped after it.
+//
+// Starting a runnable only launches its Start method, it does not wait for
+// the runnable to finish initialising. A dependency which must be initialised
+// before its dependents start, e.g. a database connection pool, has to
+// implement ReadyRunnable, otherwise its dependents may start while it is
+// still initialising.
+func NewRunnable(name string, r Runnable, dependsOn ...string) Runnable {
+	return &namedRunnable{Runnable: r, name: name, dependsOn: dependsOn}
+}
+
+// WaitReady waits for the wrapped runnable to be ready, if it is a
+// ReadyRunnable.
+func (r *namedRunnable) WaitReady(ctx context.Context) error {
+	if ready, ok := r.Runnable.(ReadyRunnable); ok {
+		return ready.WaitReady(ctx)
+	}
+
+	return nil
+}
+
+// WithRunnables registers runnables which are run together with the run
+// function of the application. The runnables are started one after another
+// in dependency order, see NewRunnable, and otherwise in registration order.
+// A runnable is only waited for before the next one is started if it
+// implements ReadyRunnable.
+// The application runs until the run function returns, or until all
+// runnables have completed if there is no run function.
+//
+// If a runnable or the run function fails, or the context of the application
+// is cancelled, e.g. by WithGracefulShutdown, the run function and the
+// runnables are stopped in reverse start order by cancelling their context,
+// each being given the shutdown timeout to return. The errors are aggregated.
+func WithRunnables(runnables ...Runnable) Option {
+	return func(a *App) {
+		a.runnables = append(a.runnables, runnables...)
+	}
+}
+
+// runnableUnit is a runnable being run.
+type runnableUnit struct {
+	name   string
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+// String returns the name of the unit used in errors.
+func (u *runnableUnit) String() string {
+	if u.name == "" {
+		return "run function"
+	}
+
+	return "runnable " + u.name
+}
+
+// runnablesFunc returns the run function which runs the runnables of the
+// application together with run, which may be nil.
+func (a *App) runnablesFunc(run RunContextFunc) RunContextFunc {
+	return func(ctx context.Context, args []string, opts CliOptions) error {
+		var start func(ctx context.Context) error
+		if run != nil {
+			start = func(ctx context.Context) error {
+				return run(ctx, args, opts)
+			}
+		}
+
+		return a.runRunnables(ctx, start)
+	}
+}
+
+// runRunnables starts the runnables in dependency order, then run, and waits
+// until run returns, all runnables have completed, one of them fails or ctx is
+// cancelled. Everything still running is then stopped in reverse start order.
+func (a *App) runRunnables(ctx context.Context, run func(ctx context.Context) error) error {
+	runnables, err := sortRunnables(a.runnables)
+	if err != nil {
+		return err
+	}
+
+	// 每个组件使用独立的 context，以便按启动的逆序逐个停止，同时保留 ctx 中的值
+	base := context.WithoutCancel(ctx)
+	failed := make(chan struct{})
+	var failOnce sync.Once
+	fail := func() { failOnce.Do(func() { close(failed) }) }
+	// readyCtx is cancelled as soon as a runnable fails or ctx is cancelled,
+	// so that waiting for a runnable to be ready does not block
+	readyCtx, cancelReady := context.WithCancel(ctx)
+	defer cancelReady()
+	go func() {
+		select {
+		case <-failed:
+			cancelReady()
+		case <-readyCtx.Done():
+		}
+	}()
+
+	var units []*runnableUnit
+	startUnit := func(name string, start func(ctx context.Context) error) *runnableUnit {
+		unitCtx, cancel := context.WithCancel(base)
+		u := &runnableUnit{name: name, cancel: cancel, done: make(chan struct{})}
+		units = append(units, u)
+		go func() {
+			defer close(u.done)
+			u.err = start(unitCtx)
+			if u.err != nil && unitCtx.Err() == nil {
+				fail()
+			}
+		}()
+
+		return u
+	}
+
+	var errs []error
+	for _, r := range runnables {
+		startUnit(r.name, r.Start)
+		if err := r.WaitReady(readyCtx); err != nil && readyCtx.Err() == nil {
+			errs = append(errs, fmt.Errorf("runnable %s is not ready: %w", r.name, err))
+			fail()
+		}
+		if readyCtx.Err() != nil {
+			break
+		}
+	}
+
+	var runUnit *runnableUnit
+	if readyCtx.Err() == nil && run != nil {
+		runUnit = startUnit("", run)
+	}
+	if readyCtx.Err() == nil {
+		waitRunnables(ctx, failed, runUnit, units)
+	}
+
+	return a.stopRunnables(ctx, units, errs)
+}
+
+// waitRunnables blocks until the run function has returned, or all runnables have
+// completed if there is none, a runnable fails or ctx is cancelled.
+func waitRunnables(ctx context.Context, failed <-chan struct{}, runUnit *runnableUnit, units []*runnableUnit) {
+	completed := make(chan struct{})
+	if runUnit != nil {
+		completed = runUnit.done
+	} else {
+		go func() {
+			for _, u := range units {
+				<-u.done
+			}
+			close(completed)
+		}()
+	}
+
+	select {
+	case <-completed:
+	case <-failed:
+	case <-ctx.Done():
+	}
+}
+
+// stopRunnables cancels the units in reverse start order, giving each of them
+// the shutdown timeout to return, and aggregates their errors with errs.
+func (a *App) stopRunnables(ctx context.Context, units []*runnableUnit, errs []error) error {
+	timeout := a.stopTimeout()
+	for i := len(units) - 1; i >= 0; i-- {
+		u := units[i]
+		u.cancel()
+		timer := time.NewTimer(timeout)
+		select {
+		case <-u.done:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+
+	for _, u := range units {
+		select {
+		case <-u.done:
+		default:
+			errs = append(errs, fmt.Errorf("%s did not stop within %s", u, timeout))
+
+			continue
+		}
+		if u.err == nil || errors.Is(u.err, context.Canceled) {
+			continue
+		}
+		if u.name == "" {
+			// the error of the run function is returned as is
+			errs = append(errs, u.err)
+		} else {
+			errs = append(errs, fmt.Errorf("%s: %w", u, u.err))
+		}
+	}
+	if len(errs) == 0 && ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	return errors.NewAggregate(errs)
+}
+
+// sortRunnables names the runnables and sorts them in dependency order, which
+// is the registration order unless a runnable depends on a later one.
+func sortRunnables(runnables []Runnable) ([]*namedRunnable, error) {
+	named := make([]*namedRunnable, len(runnables))
+	index := map[string]int{}
+	for i, r := range runnables {
+		n, ok := r.(*namedRunnable)
+		if !ok {
+			n = &namedRunnable{Runnable: r, name: fmt.Sprintf("#%d", i+1)}
+		}
+		if _, ok := index[n.name]; ok {
+			return nil, fmt.Errorf("duplicate runnable %s", n.name)
+		}
+		named[i], index[n.name] = n, i
+	}
+
+	// 按注册顺序的拓扑排序：每次选择依赖都已启动的、注册最早的组件
+	inDegree := make([]int, len(named))
+	dependents := make([][]int, len(named))
+	for i, n := range named {
+		for _, dep := range n.dependsOn {
+			j, ok := index[dep]
+			if !ok {
+				return nil, fmt.Errorf("runnable %s depends on unknown runnable %s", n.name, dep)
+			}
+			inDegree[i]++
+			dependents[j] = append(dependents[j], i)
+		}
+	}
+
+	sorted := make([]*namedRunnable, 0, len(named))
+	var ready []int
+	for i := range named {
+		if inDegree[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+	for len(ready) > 0 {
+		sort.Ints(ready)
+		i := ready[0]
+		ready = ready[1:]
+		sorted = append(sorted, named[i])
+		for _, j := range dependents[i] {
+			if inDegree[j]--; inDegree[j] == 0 {
+				ready = append(ready, j)
+			}
+		}
+	}
+	if len(sorted) != len(named) {
+		var cyclic []string
+		for i, n := range named {
+			if inDegree[i] > 0 {
+				cyclic = append(cyclic, n.name)
+			}
+		}
+
+		return nil, fmt.Errorf("runnables %v have cyclic dependencies", cyclic)
+	}
+
+	return sorted, nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// eventLog records the start and stop of the runnables in order.
+type eventLog struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (l *eventLog) add(event string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.events = append(l.events, event)
+}
+
+func (l *eventLog) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return strings.Join(l.events, ",")
+}
+
+// recordingRunnable records its start and stop, and is ready once started, so
+// that the next runnable is only started after it.
+type recordingRunnable struct {
+	name    string
+	log     *eventLog
+	started chan struct{}
+}
+
+func newRecordingRunnable(name string, log *eventLog) *recordingRunnable {
+	return &recordingRunnable{name: name, log: log, started: make(chan struct{})}
+}
+
+func (r *recordingRunnable) Start(ctx context.Context) error {
+	r.log.add("start " + r.name)
+	close(r.started)
+	<-ctx.Done()
+	r.log.add("stop " + r.name)
+
+	return nil
+}
+
+func (r *recordingRunnable) WaitReady(ctx context.Context) error {
+	select {
+	case <-r.started:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestRunnablesDependencyOrder(t *testing.T) {
+	log := &eventLog{}
+	a := NewApp("test", "test", WithNoConfig(), WithSilence(), WithIO(nil, io.Discard, io.Discard),
+		WithRunnables(
+			NewRunnable("server", newRecordingRunnable("server", log), "db", "cache"),
+			NewRunnable("cache", newRecordingRunnable("cache", log), "db"),
+			NewRunnable("db", newRecordingRunnable("db", log)),
+		),
+		WithRunContextFunc(func(context.Context, []string, CliOptions) error {
+			log.add("run")
+
+			return nil
+		}))
+	if code, err := a.Execute(context.Background(), nil); code != 0 || err != nil {
+		t.Fatalf("Execute() = %d, %v, want 0, nil", code, err)
+	}
+
+	want := "start db,start cache,start server,run,stop server,stop cache,stop db"
+	if got := log.String(); got != want {
+		t.Errorf("events = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/marmotedu/errors"
+)
+
+var shutdownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// defaultStopTimeout bounds stopping the debug server and the runnables
+// unless the application has a shutdown timeout.
+const defaultStopTimeout = 5 * time.Second
+
+// ForcedShutdownError is returned by Execute when a second shutdown signal is
+// received before the application has shut down.
+type ForcedShutdownError struct {
+	Signal os.Signal
+}
+
+// Error returns the signal which forced the shutdown.
+func (e *ForcedShutdownError) Error() string {
+	return fmt.Sprintf("shutdown forced by a second %v signal", e.Signal)
+}
+
+// ExitCode returns 128 plus the number of the signal, as shells do.
+func (e *ForcedShutdownError) ExitCode() int {
+	if s, ok := e.Signal.(syscall.Signal); ok {
+		return 128 + int(s)
+	}
+
+	return 1
+}
+
+// ShutdownHook defines a callback function which is called when the
+// application shuts down. The given context expires when the shutdown timeout
+// is reached, and a hook which has not returned by then is abandoned.
+type ShutdownHook func(ctx context.Context) error
+
+// WithGracefulShutdown enables graceful shutdown of the application. The first
+// SIGINT or SIGTERM cancels the context passed to the run function, after which
+// the shutdown hooks are given timeout to complete. A second signal makes
+// Execute return a *ForcedShutdownError immediately, without waiting for the
+// run function and the hooks, and Run terminates the process with its exit
+// code.
+func WithGracefulShutdown(timeout time.Duration) Option {
+	return func(a *App) {
+		a.gracefulShutdown = true
+		a.shutdownTimeout = timeout
+	}
+}
+
+// WithShutdownHook registers a hook which is called when the application shuts
+// down.
+func WithShutdownHook(hook ShutdownHook) Option {
+	return func(a *App) {
+		a.OnShutdown(hook)
+	}
+}
+
+// OnShutdown registers a hook which is called when the application shuts
+// down. Hooks are called in reverse registration order once the command has
+// returned. It is safe to call OnShutdown from the run function.
+func (a *App) OnShutdown(hook ShutdownHook) {
+	a.shutdownMu.Lock()
+	defer a.shutdownMu.Unlock()
+
+	a.shutdownHooks = append(a.shutdownHooks, hook)
+}
+
+// stopTimeout returns the time given to the debug server and the runnables to
+// stop.
+func (a *App) stopTimeout() time.Duration {
+	if a.shutdownTimeout > 0 {
+		return a.shutdownTimeout
+	}
+
+	return defaultStopTimeout
+}
+
+// shutdown calls the registered shutdown hooks in reverse registration order
+// and aggregates their errors. A hook which does not return within the
+// shutdown timeout is abandoned, and the hooks after it are not called.
+func (a *App) shutdown() error {
+	a.shutdownMu.Lock()
+	hooks := make([]ShutdownHook, len(a.shutdownHooks))
+	copy(hooks, a.shutdownHooks)
+	a.shutdownMu.Unlock()
+
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	ctx := context.Background()
+	if a.shutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.shutdownTimeout)
+		defer cancel()
+	}
+
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if ctx.Err() != nil {
+			errs = append(errs, fmt.Errorf("shutdown hook #%d not called, shutdown did not complete within %s", i+1, a.shutdownTimeout))
+
+			continue
+		}
+		done := make(chan error, 1)
+		go func(hook ShutdownHook) {
+			done <- hook(ctx)
+		}(hooks[i])
+		select {
+		case err := <-done:
+			if err != nil {
+				errs = append(errs, err)
+			}
+		case <-ctx.Done():
+			// 钩子函数没有遵守 ctx 的超时，不再等待它返回
+			errs = append(errs, fmt.Errorf("shutdown hook #%d abandoned, it did not return within %s", i+1, a.shutdownTimeout))
+		}
+	}
+
+	return errors.NewAggregate(errs)
+}
+
+// signalHandler cancels a context on the first shutdown signal and relays the
+// second one to forced.
+type signalHandler struct {
+	signals  chan os.Signal
+	forced   chan os.Signal
+	done     chan struct{}
+	cancel   context.CancelFunc
+	signaled atomic.Bool
+}
+
+func newSignalHandler(parent context.Context) (context.Context, *signalHandler) {
+	ctx, cancel := context.WithCancel(parent)
+	h := &signalHandler{
+		signals: make(chan os.Signal, 2),
+		forced:  make(chan os.Signal, 1),
+		done:    make(chan struct{}),
+		cancel:  cancel,
+	}
+	signal.Notify(h.signals, shutdownSignals...)
+
+	go h.wait()
+
+	return ctx, h
+}
+
+func (h *signalHandler) wait() {
+	select {
+	case <-h.signals:
+		h.signaled.Store(true)
+		h.cancel()
+	case <-h.done:
+		return
+	}
+
+	select {
+	case sig := <-h.signals:
+		h.forced <- sig
+	case <-h.done:
+	}
+}
+
+// stop stops relaying signals to the handler.
+func (h *signalHandler) stop() {
+	signal.Stop(h.signals)
+	close(h.done)
+	h.cancel()
+}
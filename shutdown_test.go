@@ -0,0 +1,48 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShutdownAbandonsHookIgnoringContext(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	called := false
+	a := NewApp("test", "test", WithNoConfig(), WithSilence(), WithIO(nil, io.Discard, io.Discard),
+		WithGracefulShutdown(50*time.Millisecond),
+		// 先注册的钩子后调用，超时后不再调用
+		WithShutdownHook(func(context.Context) error {
+			called = true
+
+			return nil
+		}),
+		WithShutdownHook(func(context.Context) error {
+			<-block
+
+			return nil
+		}),
+		WithRunContextFunc(func(context.Context, []string, CliOptions) error {
+			return nil
+		}))
+
+	start := time.Now()
+	code, err := a.Execute(context.Background(), nil)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Execute() took %s, want the shutdown timeout", elapsed)
+	}
+	if code != 1 || err == nil || !strings.Contains(err.Error(), "shutdown hook #2 abandoned") ||
+		!strings.Contains(err.Error(), "shutdown hook #1 not called") {
+		t.Errorf("Execute() = %d, %v, want the abandoned hooks", code, err)
+	}
+	if called {
+		t.Error("hook called after the shutdown timeout")
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+//go:build unix
+
+package app
+
+import (
+	"context"
+	"io"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/marmotedu/errors"
+)
+
+func TestExecuteForcedShutdown(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	a := NewApp("test", "test", WithNoConfig(), WithSilence(), WithIO(nil, io.Discard, io.Discard),
+		WithGracefulShutdown(time.Minute),
+		WithRunContextFunc(func(context.Context, []string, CliOptions) error {
+			// 运行函数忽略 ctx，只能通过第二个信号强制退出
+			for i := 0; i < 2; i++ {
+				if err := syscall.Kill(syscall.Getpid(), syscall.SIGINT); err != nil {
+					t.Error(err)
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			<-block
+
+			return nil
+		}))
+
+	code, err := a.Execute(context.Background(), nil)
+	var forced *ForcedShutdownError
+	if !errors.As(err, &forced) || forced.Signal != syscall.SIGINT {
+		t.Fatalf("Execute() error = %v, want a forced shutdown by SIGINT", err)
+	}
+	if code != 128+int(syscall.SIGINT) {
+		t.Errorf("Execute() = %d, want %d", code, 128+int(syscall.SIGINT))
+	}
+}
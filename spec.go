@@ -0,0 +1,342 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/yuanbaopig/app/flagvalue"
+	"github.com/yuanbaopig/app/fname"
+	"github.com/yuanbaopig/app/version"
+)
+
+const (
+	helpFormatFlagName = "help-format"
+	helpFormatText     = "text"
+	helpFormatJSON     = "json"
+
+	inheritedSection = "inherited"
+)
+
+// ansiEscape matches the color escape sequences of the usage messages.
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// argsProbeLimit is the number of positional arguments up to which the
+// argument rules of a command are probed.
+const argsProbeLimit = 8
+
+// Spec is the machine-readable description of the command line interface of
+// the application.
+type Spec struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description,omitempty"`
+	Version     version.Info `json:"version"`
+	Command     CommandSpec  `json:"command"`
+}
+
+// CommandSpec describes a command and its available sub commands.
+type CommandSpec struct {
+	Name     string        `json:"name"`
+	Path     string        `json:"path"`
+	Use      string        `json:"use"`
+	Short    string        `json:"short,omitempty"`
+	Long     string        `json:"long,omitempty"`
+	Example  string        `json:"example,omitempty"`
+	Aliases  []string      `json:"aliases,omitempty"`
+	Runnable bool          `json:"runnable"`
+	Args     ArgsSpec      `json:"args"`
+	Sections []SectionSpec `json:"sections,omitempty"`
+	Commands []CommandSpec `json:"commands,omitempty"`
+}
+
+// ArgsSpec describes the positional arguments accepted by a command. The
+// rules are inferred by calling the argument validator of the command with
+// placeholder arguments, so custom validators which check the values of the
+// arguments may report less than they accept.
+type ArgsSpec struct {
+	// Min is the minimum number of arguments.
+	Min int `json:"min"`
+	// Max is the maximum number of arguments, -1 if unbounded.
+	Max int `json:"max"`
+	// ValidArgs are the only values accepted, if any.
+	ValidArgs []string `json:"validArgs,omitempty"`
+}
+
+// SectionSpec describes a named flag set, in the order of
+// fname.NamedFlagSets.Order.
+type SectionSpec struct {
+	Name        string     `json:"name"`
+	Flags       []FlagSpec `json:"flags"`
+	Constraints []string   `json:"constraints,omitempty"`
+}
+
+// FlagSpec describes a flag. Key and Env are set if the flag is also a
+// configuration key, which can be set by the configuration file and the
+// environment variable.
+type FlagSpec struct {
+	Name          string   `json:"name"`
+	Shorthand     string   `json:"shorthand,omitempty"`
+	Type          string   `json:"type"`
+	Default       string   `json:"default"`
+	Usage         string   `json:"usage"`
+	Key           string   `json:"key,omitempty"`
+	Env           string   `json:"env,omitempty"`
+	Sensitive     bool     `json:"sensitive,omitempty"`
+	AllowedValues []string `json:"allowedValues,omitempty"`
+}
+
+// Spec returns the machine-readable description of the command line interface
+// of the application, which is also printed by --help-format=json.
+func (a *App) Spec() Spec {
+	return Spec{
+		Name:        a.name,
+		Description: a.description,
+		Version:     version.Get(),
+		Command:     a.commandSpec(a.cmd),
+	}
+}
+
+func (a *App) commandSpec(cmd *cobra.Command) CommandSpec {
+	spec := CommandSpec{
+		Name:     cmd.Name(),
+		Path:     cmd.CommandPath(),
+		Use:      cmd.UseLine(),
+		Short:    cmd.Short,
+		Long:     cmd.Long,
+		Example:  cmd.Example,
+		Aliases:  cmd.Aliases,
+		Runnable: cmd.Runnable(),
+		Args:     argsSpec(cmd),
+		Sections: a.sectionSpecs(a.commandFlagSets(cmd)),
+	}
+	for _, sub := range cmd.Commands() {
+		if sub.IsAvailableCommand() {
+			spec.Commands = append(spec.Commands, a.commandSpec(sub))
+		}
+	}
+
+	return spec
+}
+
+// commandFlagSets returns the flags of cmd in sections: the named flag sets
+// of the application or the command, or the local flags of the other
+// commands, followed by the flags inherited from the parent commands in the
+// sections of their parent, e.g. "inherited global".
+func (a *App) commandFlagSets(cmd *cobra.Command) fname.NamedFlagSets {
+	fss, ok := a.flagSets[cmd]
+	if !ok {
+		fss = fname.NamedFlagSets{}
+		fss.FlagSet("flags").AddFlagSet(cmd.LocalFlags())
+	}
+
+	sections := fname.NamedFlagSets{
+		Order:       append([]string{}, fss.Order...),
+		FlagSets:    map[string]*pflag.FlagSet{},
+		Constraints: fss.Constraints,
+	}
+	for name, fs := range fss.FlagSets {
+		sections.FlagSets[name] = fs
+	}
+
+	inherited := cmd.InheritedFlags()
+	addInherited := func(section string, flag *pflag.Flag) {
+		if inherited.Lookup(flag.Name) == flag && !hasFlag(sections, flag.Name) {
+			sections.FlagSet(section).AddFlag(flag)
+		}
+	}
+	for p := cmd.Parent(); p != nil; p = p.Parent() {
+		parent := a.flagSets[p]
+		for _, name := range parent.Order {
+			parent.FlagSets[name].VisitAll(func(flag *pflag.Flag) {
+				addInherited(inheritedSection+" "+name, flag)
+			})
+		}
+	}
+	inherited.VisitAll(func(flag *pflag.Flag) {
+		addInherited(inheritedSection, flag)
+	})
+
+	return sections
+}
+
+func hasFlag(fss fname.NamedFlagSets, name string) bool {
+	for _, fs := range fss.FlagSets {
+		if fs.Lookup(name) != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sectionSpecs returns the description of the non-empty sections of fss.
+func (a *App) sectionSpecs(fss fname.NamedFlagSets) []SectionSpec {
+	var sections []SectionSpec
+	for _, name := range fss.Order {
+		fs := fss.FlagSets[name]
+		section := SectionSpec{Name: name}
+		fs.VisitAll(func(flag *pflag.Flag) {
+			if !flag.Hidden {
+				section.Flags = append(section.Flags, a.flagSpec(flag))
+			}
+		})
+		if len(section.Flags) == 0 {
+			continue
+		}
+		for _, c := range fss.Constraints {
+			if len(c.Flags) > 0 && fs.Lookup(c.Flags[0]) != nil {
+				section.Constraints = append(section.Constraints, c.String())
+			}
+		}
+		sections = append(sections, section)
+	}
+
+	return sections
+}
+
+func (a *App) flagSpec(flag *pflag.Flag) FlagSpec {
+	_, usage := pflag.UnquoteUsage(flag)
+	spec := FlagSpec{
+		Name:      flag.Name,
+		Shorthand: flag.Shorthand,
+		Type:      flag.Value.Type(),
+		Default:   defaultValue(flag),
+		Usage:     ansiEscape.ReplaceAllString(usage, ""),
+		Sensitive: fname.IsSensitive(flag),
+	}
+	if value, ok := flag.Value.(enumValue); ok {
+		spec.AllowedValues = value.AllowedValues()
+	}
+	if a.isConfigFlag(flag) {
+		spec.Key = strings.ToLower(flag.Name)
+		spec.Env = a.envVarName(spec.Key)
+	}
+
+	return spec
+}
+
+// defaultValue returns the default value of the flag, or RedactedValue if the
+// flag is marked as sensitive and has a default value.
+func defaultValue(flag *pflag.Flag) string {
+	if fname.IsSensitive(flag) && flag.DefValue != "" {
+		return fname.RedactedValue
+	}
+
+	return flag.DefValue
+}
+
+// isConfigFlag reports whether the flag is bound to a configuration key,
+// which is the case of the flags of the options of the application and its
+// commands, and of the feature gates.
+func (a *App) isConfigFlag(flag *pflag.Flag) bool {
+	if a.noConfig || isBuiltinKey(strings.ToLower(flag.Name)) {
+		return false
+	}
+	for _, fss := range a.flagSets {
+		for _, fs := range fss.FlagSets {
+			if fs.Lookup(flag.Name) == flag {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// argsSpec infers the positional arguments accepted by cmd by validating
+// up to argsProbeLimit placeholder arguments.
+func argsSpec(cmd *cobra.Command) ArgsSpec {
+	placeholder := "arg"
+	spec := ArgsSpec{Min: -1, Max: -1}
+	for _, arg := range cmd.ValidArgs {
+		// 有效参数中可能包含以制表符分隔的描述信息
+		arg, _, _ = strings.Cut(arg, "\t")
+		spec.ValidArgs = append(spec.ValidArgs, arg)
+	}
+	if len(spec.ValidArgs) > 0 {
+		placeholder = spec.ValidArgs[0]
+	}
+	if cmd.Args == nil && cmd.HasSubCommands() && !cmd.HasParent() {
+		// cobra 将根命令的参数视为子命令名称，未知的子命令会报错
+		spec.Min, spec.Max = 0, 0
+
+		return spec
+	}
+
+	for n := 0; n <= argsProbeLimit; n++ {
+		args := make([]string, n)
+		for i := range args {
+			args[i] = placeholder
+		}
+		if !validArgs(cmd, args) {
+			if spec.Min >= 0 && spec.Max < 0 {
+				spec.Max = n - 1
+			}
+
+			continue
+		}
+		if spec.Min < 0 {
+			spec.Min = n
+		}
+	}
+	if spec.Min < 0 {
+		spec.Min, spec.Max = 0, 0
+	}
+
+	return spec
+}
+
+// validArgs reports whether cmd accepts args. A validator which panics, e.g.
+// by indexing the arguments without checking their number, rejects them.
+func validArgs(cmd *cobra.Command, args []string) (ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+
+	return cmd.ValidateArgs(args) == nil
+}
+
+// registerFlagSets records the flag sections of cmd, which are rendered in
+// its specification and documentation.
+func (a *App) registerFlagSets(cmd *cobra.Command, fss fname.NamedFlagSets) {
+	if a.flagSets == nil {
+		a.flagSets = map[*cobra.Command]fname.NamedFlagSets{}
+	}
+	a.flagSets[cmd] = fss
+}
+
+// addHelpFormatFlag adds the help-format flag, which selects the format of
+// the help information, to fs.
+func (a *App) addHelpFormatFlag(fs *pflag.FlagSet) {
+	a.helpFormat = flagvalue.NewEnum(helpFormatText, helpFormatText, helpFormatJSON)
+	fs.Var(&a.helpFormat, helpFormatFlagName, "Format of the help information. "+
+		"The json format prints the specification of the whole command line interface.")
+}
+
+// printSpecIfRequested prints the specification of the application as JSON
+// to w and returns true if --help-format=json is given.
+func (a *App) printSpecIfRequested(w io.Writer) bool {
+	if a.helpFormat.Value != helpFormatJSON {
+		return false
+	}
+
+	data, err := json.MarshalIndent(a.Spec(), "", "  ")
+	if err != nil {
+		fmt.Fprintf(w, "%v\n", err)
+
+		return true
+	}
+	fmt.Fprintf(w, "%s\n", data)
+
+	return true
+}
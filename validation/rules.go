@@ -0,0 +1,397 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package validation
+
+import (
+	"encoding"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RuleFunc checks the value of a field against the parameter of the rule. The
+// message of the returned error describes the violation.
+type RuleFunc func(f Field) error
+
+// Field is a struct field checked by a rule.
+type Field struct {
+	// Value is the value of the field.
+	Value reflect.Value
+	// Param is the parameter of the rule.
+	Param string
+
+	parent reflect.Value
+	prefix string
+	v      *Validator
+}
+
+// Sibling returns the value and the configuration key of the field with the
+// given name in the struct holding f, which cross-field rules compare f with.
+func (f Field) Sibling(name string) (reflect.Value, string, error) {
+	field, ok := f.parent.Type().FieldByName(name)
+	if !ok {
+		return reflect.Value{}, "", fmt.Errorf("references unknown field %s", name)
+	}
+	key, squash := f.v.fieldKey(field)
+	if !squash {
+		key = joinPath(f.prefix, key)
+	}
+
+	return f.parent.FieldByIndex(field.Index), key, nil
+}
+
+var (
+	rulesMu sync.RWMutex
+	rules   = map[string]RuleFunc{
+		"required":         required,
+		"min":              minimum,
+		"max":              maximum,
+		"len":              length,
+		"oneof":            oneOf,
+		"hostport":         hostPort,
+		"url":              isURL,
+		"ip":               isIP,
+		"file_exists":      fileExists,
+		"dir_exists":       dirExists,
+		"required_with":    requiredWith,
+		"required_without": requiredWithout,
+		"required_if":      requiredIf,
+		"eqfield":          compareField("eqfield", false, func(c int) bool { return c == 0 }, "must be equal to"),
+		"nefield":          compareField("nefield", false, func(c int) bool { return c != 0 }, "must not be equal to"),
+		"gtfield":          compareField("gtfield", true, func(c int) bool { return c > 0 }, "must be greater than"),
+		"gtefield":         compareField("gtefield", true, func(c int) bool { return c >= 0 }, "must be greater than or equal to"),
+		"ltfield":          compareField("ltfield", true, func(c int) bool { return c < 0 }, "must be less than"),
+		"ltefield":         compareField("ltefield", true, func(c int) bool { return c <= 0 }, "must be less than or equal to"),
+	}
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Register registers a custom validation rule, replacing the builtin rule
+// with the same name, if any.
+func Register(name string, fn RuleFunc) {
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+
+	rules[name] = fn
+}
+
+func lookupRule(name string) RuleFunc {
+	rulesMu.RLock()
+	defer rulesMu.RUnlock()
+
+	return rules[name]
+}
+
+func required(f Field) error {
+	field := f.Value
+	if isEmpty(field) {
+		return fmt.Errorf("is required")
+	}
+
+	return nil
+}
+
+func minimum(f Field) error {
+	field, param := f.Value, f.Param
+	c, err := compareParam(field, param)
+	if err != nil {
+		return err
+	}
+	if c < 0 {
+		return fmt.Errorf("%s at least %s", measure(field), param)
+	}
+
+	return nil
+}
+
+func maximum(f Field) error {
+	field, param := f.Value, f.Param
+	c, err := compareParam(field, param)
+	if err != nil {
+		return err
+	}
+	if c > 0 {
+		return fmt.Errorf("%s at most %s", measure(field), param)
+	}
+
+	return nil
+}
+
+func length(f Field) error {
+	field, param := f.Value, f.Param
+	c, err := compareParam(field, param)
+	if err != nil {
+		return err
+	}
+	if c != 0 {
+		return fmt.Errorf("%s exactly %s", measure(field), param)
+	}
+
+	return nil
+}
+
+func oneOf(f Field) error {
+	field, param := f.Value, f.Param
+	values := strings.Fields(param)
+	value := fmt.Sprint(field.Interface())
+	for _, v := range values {
+		if v == value {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("must be one of: %s", strings.Join(values, ", "))
+}
+
+func hostPort(f Field) error {
+	value := fieldText(f.Value)
+	_, port, err := net.SplitHostPort(value)
+	if err != nil {
+		return fmt.Errorf("must be a host:port address")
+	}
+	if p, err := strconv.ParseUint(port, 10, 16); err != nil || p == 0 {
+		return fmt.Errorf("must be a host:port address with a port between 1 and 65535")
+	}
+
+	return nil
+}
+
+func isURL(f Field) error {
+	value := fieldText(f.Value)
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("must be an absolute URL")
+	}
+
+	return nil
+}
+
+func isIP(f Field) error {
+	value := fieldText(f.Value)
+	if net.ParseIP(value) == nil {
+		return fmt.Errorf("must be an IP address")
+	}
+
+	return nil
+}
+
+func fileExists(f Field) error {
+	value := fieldText(f.Value)
+	info, err := os.Stat(value)
+	if err != nil || info.IsDir() {
+		return fmt.Errorf("file %q does not exist", value)
+	}
+
+	return nil
+}
+
+func dirExists(f Field) error {
+	value := fieldText(f.Value)
+	info, err := os.Stat(value)
+	if err != nil || !info.IsDir() {
+		return fmt.Errorf("directory %q does not exist", value)
+	}
+
+	return nil
+}
+
+func requiredWith(f Field) error {
+	other, key, err := f.Sibling(f.Param)
+	if err != nil {
+		return err
+	}
+	if !isEmpty(other) && isEmpty(f.Value) {
+		return fmt.Errorf("is required when %s is set", key)
+	}
+
+	return nil
+}
+
+func requiredWithout(f Field) error {
+	other, key, err := f.Sibling(f.Param)
+	if err != nil {
+		return err
+	}
+	if isEmpty(other) && isEmpty(f.Value) {
+		return fmt.Errorf("is required when %s is not set", key)
+	}
+
+	return nil
+}
+
+func requiredIf(f Field) error {
+	name, value, _ := strings.Cut(f.Param, " ")
+	other, key, err := f.Sibling(name)
+	if err != nil {
+		return err
+	}
+	if fmt.Sprint(other.Interface()) == value && isEmpty(f.Value) {
+		return fmt.Errorf("is required when %s is %s", key, value)
+	}
+
+	return nil
+}
+
+// compareField returns a cross-field rule which compares the field with the
+// field named by the parameter and accepts the comparison result if ok
+// returns true. Rules which need an order only accept numbers and strings.
+func compareField(rule string, ordered bool, ok func(int) bool, desc string) RuleFunc {
+	return func(f Field) error {
+		other, key, err := f.Sibling(f.Param)
+		if err != nil {
+			return err
+		}
+		c, isOrdered := compareValues(f.Value, other)
+		if ordered && !isOrdered {
+			return fmt.Errorf("cannot be compared with %s by %s", key, rule)
+		}
+		if !ok(c) {
+			return fmt.Errorf("%s %s", desc, key)
+		}
+
+		return nil
+	}
+}
+
+// isEmpty reports whether v has its zero value or is an empty slice or map.
+// fieldText returns the text of the field checked by the string rules. Fields
+// which are not strings, such as flagvalue.URL, are formatted by their String
+// or MarshalText method, looked up on their pointer as well.
+func fieldText(v reflect.Value) string {
+	if v.Kind() == reflect.String {
+		return v.String()
+	}
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return ""
+	}
+
+	candidates := []reflect.Value{v}
+	if v.CanAddr() {
+		candidates = append(candidates, v.Addr())
+	}
+	for _, c := range candidates {
+		if !c.CanInterface() {
+			continue
+		}
+		switch value := c.Interface().(type) {
+		case fmt.Stringer:
+			return value.String()
+		case encoding.TextMarshaler:
+			if text, err := value.MarshalText(); err == nil {
+				return string(text)
+			}
+		}
+	}
+	if !v.CanInterface() {
+		return ""
+	}
+
+	return fmt.Sprint(v.Interface())
+}
+
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	default:
+		return v.IsZero()
+	}
+}
+
+// measure describes what min, max and len compare for the kind of v.
+func measure(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.String:
+		return "length must be"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return "number of items must be"
+	default:
+		return "must be"
+	}
+}
+
+// compareParam compares v with the parameter of a rule: the length of strings,
+// slices, maps and arrays, or the value of numbers and durations.
+func compareParam(v reflect.Value, param string) (int, error) {
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		n, err := strconv.Atoi(param)
+		if err != nil {
+			return 0, fmt.Errorf("invalid length %q", param)
+		}
+
+		return compare(float64(v.Len()), float64(n)), nil
+	}
+
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(param)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", param)
+		}
+
+		return compare(float64(v.Int()), float64(d)), nil
+	}
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", param)
+	}
+	f, ok := toFloat(v)
+	if !ok {
+		return 0, fmt.Errorf("cannot be compared with %s", param)
+	}
+
+	return compare(f, n), nil
+}
+
+// compareValues compares two numbers or two strings, and reports whether the
+// values are ordered. Other values are only compared for equality, in which
+// case a non-zero result means they differ.
+func compareValues(a, b reflect.Value) (int, bool) {
+	if a.Kind() == reflect.String && b.Kind() == reflect.String {
+		return strings.Compare(a.String(), b.String()), true
+	}
+	x, ok1 := toFloat(a)
+	y, ok2 := toFloat(b)
+	if !ok1 || !ok2 {
+		if reflect.DeepEqual(a.Interface(), b.Interface()) {
+			return 0, false
+		}
+
+		return 1, false
+	}
+
+	return compare(x, y), true
+}
+
+func toFloat(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	default:
+		return 0, false
+	}
+}
+
+func compare(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package validation
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yuanbaopig/app/flagvalue"
+)
+
+type valueOptions struct {
+	URL      flagvalue.URL          `validate:"url"`
+	HostPort flagvalue.HostPort     `validate:"hostport"`
+	IP       net.IP                 `validate:"ip"`
+	File     flagvalue.ExistingFile `validate:"file_exists"`
+	Dir      flagvalue.ExistingDir  `validate:"dir_exists"`
+}
+
+func TestStringRulesOnValueTypes(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	valid := &valueOptions{IP: net.ParseIP("127.0.0.1"), File: flagvalue.ExistingFile(file), Dir: flagvalue.ExistingDir(dir)}
+	if err := valid.URL.Set("https://example.com/path"); err != nil {
+		t.Fatal(err)
+	}
+	if err := valid.HostPort.Set("127.0.0.1:8080"); err != nil {
+		t.Fatal(err)
+	}
+	if errs := Struct(valid); len(errs) != 0 {
+		t.Errorf("Struct(valid) = %v, want no errors", errs)
+	}
+
+	invalid := &valueOptions{
+		HostPort: flagvalue.HostPort{Host: "127.0.0.1"},
+		File:     flagvalue.ExistingFile(dir),
+		Dir:      flagvalue.ExistingDir(file),
+	}
+	if err := invalid.URL.Set("https:///path"); err != nil {
+		t.Fatal(err)
+	}
+	errs := Struct(invalid)
+	rules := map[string]bool{}
+	for _, err := range errs {
+		rules[err.(*FieldError).Rule] = true
+	}
+	for _, rule := range []string{"url", "hostport", "ip", "file_exists", "dir_exists"} {
+		if !rules[rule] {
+			t.Errorf("Struct(invalid) did not violate %s, got %v", rule, errs)
+		}
+	}
+}
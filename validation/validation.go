@@ -0,0 +1,202 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+// Package validation validates options structs against the rules declared in
+// their validate struct tags, e.g.
+//
+//	Port int    `validate:"required,min=1,max=65535"`
+//	Mode string `validate:"oneof=debug info"`
+//	Cert string `validate:"required_with=Key,omitempty,file_exists"`
+//
+// Rules are separated by commas and their parameter follows an equal sign.
+// The omitempty rule skips the remaining rules when the field has its zero
+// value. Cross-field rules take the name of a field of the same struct as
+// parameter.
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Tag is the struct tag holding the validation rules of a field.
+const Tag = "validate"
+
+// FieldError describes a field which violates a validation rule.
+type FieldError struct {
+	// Field is the path of the struct field, e.g. "MySQL.Port".
+	Field string
+	// Key is the configuration key of the field, e.g. "mysql.port".
+	Key string
+	// Flag is the command line flag bound to the field, e.g. "--mysql.port",
+	// or empty if there is none.
+	Flag string
+	// Env is the environment variable bound to the field, or empty if there
+	// is none.
+	Env string
+	// Rule is the name of the violated rule, e.g. "max".
+	Rule string
+	// Message describes the violation, e.g. "must be at most 65535".
+	Message string
+}
+
+// Error returns the configuration key and the message of the violation,
+// followed by the flag and the environment variable which set the field.
+func (e *FieldError) Error() string {
+	var refs []string
+	if e.Flag != "" {
+		refs = append(refs, "flag "+e.Flag)
+	}
+	if e.Env != "" {
+		refs = append(refs, "env "+e.Env)
+	}
+	if len(refs) == 0 {
+		return fmt.Sprintf("%s: %s", e.Key, e.Message)
+	}
+
+	return fmt.Sprintf("%s: %s (%s)", e.Key, e.Message, strings.Join(refs, ", "))
+}
+
+// Validator validates options structs.
+type Validator struct {
+	// TagName is the struct tag naming the configuration keys of the fields,
+	// "mapstructure" if empty. Fields without tag are named by their
+	// lower-case field name, as viper.Unmarshal does.
+	TagName string
+	// SquashEmbedded adds the keys of the fields of embedded structs without
+	// name to the parent.
+	SquashEmbedded bool
+	// FlagName returns the flag bound to the configuration key, if any.
+	FlagName func(key string) string
+	// EnvName returns the environment variable bound to the configuration
+	// key, if any.
+	EnvName func(key string) string
+}
+
+// Struct validates the struct s, or the struct s points to, with the default
+// Validator.
+func Struct(s interface{}) []error {
+	return (&Validator{}).Struct(s)
+}
+
+// Struct validates the struct s, or the struct s points to, and its nested
+// structs, and returns a *FieldError for every violated rule.
+func (v *Validator) Struct(s interface{}) []error {
+	var errs []error
+	v.validateStruct(reflect.ValueOf(s), "", "", map[reflect.Type]bool{}, &errs)
+
+	return errs
+}
+
+func (v *Validator) validateStruct(sv reflect.Value, path, prefix string, parents map[reflect.Type]bool, errs *[]error) {
+	for sv.Kind() == reflect.Ptr || sv.Kind() == reflect.Interface {
+		if sv.IsNil() {
+			return
+		}
+		sv = sv.Elem()
+	}
+	if sv.Kind() != reflect.Struct || parents[sv.Type()] {
+		return
+	}
+
+	t := sv.Type()
+	parents[t] = true
+	defer delete(parents, t)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name, squash := v.fieldKey(field)
+		if name == "-" {
+			continue
+		}
+		key := prefix
+		if !squash {
+			key = joinPath(prefix, name)
+		}
+		fieldPath := joinPath(path, field.Name)
+
+		fv := sv.Field(i)
+		if tag := field.Tag.Get(Tag); tag != "" {
+			v.validateField(Field{Value: fv, parent: sv, prefix: prefix, v: v}, tag, fieldPath, key, errs)
+		}
+		if ft := derefType(field.Type); ft.Kind() == reflect.Struct {
+			v.validateStruct(fv, fieldPath, key, parents, errs)
+		}
+	}
+}
+
+func (v *Validator) validateField(f Field, tag, path, key string, errs *[]error) {
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		if name == "" {
+			continue
+		}
+		if name == "omitempty" {
+			if isEmpty(f.Value) {
+				return
+			}
+
+			continue
+		}
+
+		f.Param = param
+		var msg string
+		if fn := lookupRule(name); fn == nil {
+			msg = fmt.Sprintf("unknown validation rule %q", name)
+		} else if err := fn(f); err != nil {
+			msg = err.Error()
+		} else {
+			continue
+		}
+
+		fieldErr := &FieldError{Field: path, Key: key, Rule: name, Message: msg}
+		if v.FlagName != nil {
+			fieldErr.Flag = v.FlagName(key)
+		}
+		if v.EnvName != nil {
+			fieldErr.Env = v.EnvName(key)
+		}
+		*errs = append(*errs, fieldErr)
+	}
+}
+
+// fieldKey returns the configuration key segment of the struct field and
+// whether its fields are squashed into the parent.
+func (v *Validator) fieldKey(field reflect.StructField) (string, bool) {
+	tagName := v.TagName
+	if tagName == "" {
+		tagName = "mapstructure"
+	}
+	name, opts, _ := strings.Cut(field.Tag.Get(tagName), ",")
+	squash := v.SquashEmbedded && field.Anonymous && name == ""
+	for _, opt := range strings.Split(opts, ",") {
+		if opt == "squash" {
+			squash = true
+		}
+	}
+	if name == "" {
+		name = field.Name
+	}
+
+	return strings.ToLower(name), squash
+}
+
+func joinPath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+
+	return prefix + "." + name
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t
+}
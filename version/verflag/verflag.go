@@ -9,6 +9,7 @@ package verflag
 import (
 	"fmt"
 	"github.com/yuanbaopig/app/version"
+	"io"
 	"os"
 	"strconv"
 
@@ -22,10 +23,30 @@ const (
 	VersionFalse versionValue = 0
 	VersionTrue  versionValue = 1
 	VersionRaw   versionValue = 2
+	VersionJSON  versionValue = 3
+	VersionYAML  versionValue = 4
+	VersionShort versionValue = 5
 )
 
 const strRawVersion string = "raw"
 
+// Define the output formats of the version information.
+const (
+	OutputText  = "text"
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+	OutputShort = "short"
+)
+
+// outputs are the output formats accepted by the version flag besides the
+// boolean values.
+var outputs = map[string]versionValue{
+	strRawVersion: VersionRaw,
+	OutputJSON:    VersionJSON,
+	OutputYAML:    VersionYAML,
+	OutputShort:   VersionShort,
+}
+
 func (v *versionValue) IsBoolFlag() bool {
 	return true
 }
@@ -35,22 +56,27 @@ func (v *versionValue) Get() interface{} {
 }
 
 func (v *versionValue) Set(s string) error {
-	if s == strRawVersion {
-		*v = VersionRaw
+	if value, ok := outputs[s]; ok {
+		*v = value
 		return nil
 	}
 	boolVal, err := strconv.ParseBool(s)
+	if err != nil {
+		return fmt.Errorf("must be a boolean, %s, %s, %s or %s", OutputJSON, OutputYAML, OutputShort, strRawVersion)
+	}
 	if boolVal {
 		*v = VersionTrue
 	} else {
 		*v = VersionFalse
 	}
-	return err
+	return nil
 }
 
 func (v *versionValue) String() string {
-	if *v == VersionRaw {
-		return strRawVersion
+	for s, value := range outputs {
+		if *v == value {
+			return s
+		}
 	}
 	return fmt.Sprintf("%v", bool(*v == VersionTrue))
 }
@@ -77,25 +103,67 @@ func Version(name string, value versionValue, usage string) *versionValue {
 
 const versionFlagName = "version"
 
-var versionFlag *versionValue
-
-// AddFlags registers this package's flags on arbitrary FlagSets, such that they point to the
-// same value as the global flags.
+// AddFlags registers this package's flags on arbitrary FlagSets. Every FlagSet
+// gets its own version value, so that flag sets do not share any state.
 func AddFlags(fs *flag.FlagSet) {
-	
-	Version(versionFlagName, VersionFalse, "Print version information and quit.")
+	p := new(versionValue)
+	*p = VersionFalse
+	fs.Var(p, versionFlagName, "Print version information and quit. "+
+		"Use --version=json, --version=yaml or --version=short for other formats.")
+	// "--version" will be treated as "--version=true"
+	fs.Lookup(versionFlagName).NoOptDefVal = "true"
+}
 
-	fs.AddFlag(flag.Lookup(versionFlagName))
+// PrintIfRequested will check if the -version flag registered on fs was passed
+// and, if so, print the version to w and return true.
+func PrintIfRequested(fs *flag.FlagSet, w io.Writer) bool {
+	f := fs.Lookup(versionFlagName)
+	if f == nil {
+		return false
+	}
+	v, ok := f.Value.(*versionValue)
+	if !ok {
+		return false
+	}
+
+	switch *v {
+	case VersionRaw:
+		fmt.Fprintf(w, "%#v\n", version.Get())
+	case VersionTrue:
+		_ = Print(w, OutputText)
+	case VersionJSON, VersionYAML, VersionShort:
+		_ = Print(w, v.String())
+	default:
+		return false
+	}
+
+	return true
 }
 
-// PrintAndExitIfRequested will check if the -version flag was passed
-// and, if so, print the version and exit.
+// Print prints the version information to w in the output format: text, json,
+// yaml, or short for the version number only.
+func Print(w io.Writer, output string) error {
+	info := version.Get()
+	switch output {
+	case OutputText:
+		fmt.Fprintf(w, "%s\n", info)
+	case OutputJSON:
+		fmt.Fprintf(w, "%s\n", info.ToJSON())
+	case OutputYAML:
+		fmt.Fprint(w, info.ToYAML())
+	case OutputShort:
+		fmt.Fprintf(w, "%s\n", info.GitVersion)
+	default:
+		return fmt.Errorf("unknown version output format %q", output)
+	}
+
+	return nil
+}
+
+// PrintAndExitIfRequested will check if the -version flag was passed on the
+// global FlagSet and, if so, print the version and exit.
 func PrintAndExitIfRequested() {
-	if *versionFlag == VersionRaw {
-		fmt.Printf("%#v\n", version.Get())
-		os.Exit(0)
-	} else if *versionFlag == VersionTrue {
-		fmt.Printf("%s\n", version.Get())
+	if PrintIfRequested(flag.CommandLine, os.Stdout) {
 		os.Exit(0)
 	}
 }
@@ -12,6 +12,7 @@ import (
 	"runtime"
 
 	"github.com/gosuri/uitable"
+	"gopkg.in/yaml.v3"
 )
 
 var (
@@ -27,13 +28,13 @@ var (
 
 // Info contains versioning information.
 type Info struct {
-	GitVersion   string `json:"gitVersion"`
-	GitCommit    string `json:"gitCommit"`
-	GitTreeState string `json:"gitTreeState"`
-	BuildDate    string `json:"buildDate"`
-	GoVersion    string `json:"goVersion"`
-	Compiler     string `json:"compiler"`
-	Platform     string `json:"platform"`
+	GitVersion   string `json:"gitVersion" yaml:"gitVersion"`
+	GitCommit    string `json:"gitCommit" yaml:"gitCommit"`
+	GitTreeState string `json:"gitTreeState" yaml:"gitTreeState"`
+	BuildDate    string `json:"buildDate" yaml:"buildDate"`
+	GoVersion    string `json:"goVersion" yaml:"goVersion"`
+	Compiler     string `json:"compiler" yaml:"compiler"`
+	Platform     string `json:"platform" yaml:"platform"`
 }
 
 // String returns info as a human-friendly version string.
@@ -70,6 +71,13 @@ func (info Info) Text() ([]byte, error) {
 	return table.Bytes(), nil
 }
 
+// ToYAML returns the version information as YAML.
+func (info Info) ToYAML() string {
+	s, _ := yaml.Marshal(info)
+
+	return string(s)
+}
+
 // Get returns the overall codebase version. It's for detecting
 // what code a binary was built from.
 func Get() Info {
@@ -0,0 +1,51 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/yuanbaopig/app/flagvalue"
+	"github.com/yuanbaopig/app/version/verflag"
+)
+
+// versionCommand returns the version command, which prints the version
+// information like the version flag.
+func (a *App) versionCommand() *cobra.Command {
+	output := flagvalue.NewEnum(verflag.OutputText, verflag.OutputText, verflag.OutputJSON, verflag.OutputYAML)
+	var short bool
+
+	cmd := &cobra.Command{
+		Use:   "version",
+		Short: "Print the version information.",
+		Args:  cobra.NoArgs,
+		// 输出版本信息不需要读取配置文件
+		PersistentPreRunE: func(*cobra.Command, []string) error {
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if short {
+				return verflag.Print(cmd.OutOrStdout(), verflag.OutputShort)
+			}
+
+			return verflag.Print(cmd.OutOrStdout(), output.Value)
+		},
+	}
+	cmd.Flags().VarP(&output, "output", "o", "Output format of the version information.")
+	cmd.Flags().BoolVar(&short, "short", false, "Print the version number only.")
+	cmd.MarkFlagsMutuallyExclusive("output", "short")
+
+	return cmd
+}
+
+// hasCommand reports whether cmd has a sub command with the given name.
+func hasCommand(cmd *cobra.Command, name string) bool {
+	for _, c := range cmd.Commands() {
+		if c.Name() == name {
+			return true
+		}
+	}
+
+	return false
+}